@@ -0,0 +1,93 @@
+package backend
+
+import (
+	"errors"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/backend/datastore"
+	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+var (
+	ErrInvalidRate = errors.New("invalid update rate")
+)
+
+// IndexLookupFailing returns a page of podcasts with crawl errors, only the suspended
+// or dead ones if these are set
+func IndexLookupFailing(suspended bool, dead bool, page int, size int) (*PodcastIndexPage, error) {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	q := bson.M{"errors": bson.M{"$gt": 0}}
+	if suspended {
+		q = bson.M{"errors": bson.M{"$gt": MAX_ERRORS}}
+	}
+	if dead {
+		q = bson.M{"dead": true}
+	}
+
+	total, err := main_index.Find(q).Count()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []PodcastIndex{}
+	err = main_index.Find(q).Sort("-errors", "-updated").Skip((page - 1) * size).Limit(size).All(&entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PodcastIndexPage{page, size, total, entries}, nil
+}
+
+// IndexResume clears the errors of a podcast and schedules it right away
+func IndexResume(uid string) error {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	return main_index.Update(bson.M{"uid": uid}, bson.M{"$set": bson.M{"errors": 0, "next": util.Timestamp(), "failingsince": 0, "dead": false}})
+}
+
+// IndexSetRate fixes the update rate of a podcast, a rate of 0 returns it to adaptive scheduling
+func IndexSetRate(uid string, rate int) error {
+
+	if rate < 0 {
+		return ErrInvalidRate
+	}
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	if rate == 0 {
+		return main_index.Update(bson.M{"uid": uid}, bson.M{"$set": bson.M{"fixedrate": false}})
+	}
+
+	next := util.IncT(util.Timestamp(), rate)
+	return main_index.Update(bson.M{"uid": uid}, bson.M{"$set": bson.M{"updaterate": rate, "next": next, "fixedrate": true}})
+}
+
+// AdminAuditLog records an admin action
+func AdminAuditLog(user string, action string, uid string, detail string) {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	admin_audit := ds.Collection(datastore.ADMIN_AUDIT_COL)
+
+	err := admin_audit.Insert(&AdminAudit{user, action, uid, detail, util.Timestamp()})
+	if err != nil {
+		logger.Error("admin_audit_log.error", err, user, action, uid)
+	}
+
+	logger.Log("admin_audit_log", user, action, uid, detail)
+}
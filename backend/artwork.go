@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/backend/datastore"
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+// ArtworkClaim picks up to limit podcasts whose artwork is due. Each one is pushed back by
+// ARTWORK_LEASE with an atomic find-and-modify, so that no two crawlers process the same artwork.
+func ArtworkClaim(limit int) []PodcastMetadata {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	podcast_metadata := ds.Collection(datastore.PODCASTS_COL)
+
+	results := []PodcastMetadata{}
+	for len(results) < limit {
+		now := util.Timestamp()
+
+		q := bson.M{
+			"imageurl":    bson.M{"$ne": ""},
+			"mergedinto":  bson.M{"$in": []interface{}{"", nil}},
+			"artworknext": bson.M{"$not": bson.M{"$gte": now}},
+		}
+		change := mgo.Change{
+			Update:    bson.M{"$set": bson.M{"artworknext": now + ARTWORK_LEASE}},
+			ReturnNew: true,
+		}
+
+		p := PodcastMetadata{}
+		_, err := podcast_metadata.Find(q).Sort("artworknext").Apply(change, &p)
+		if err != nil {
+			break // mgo.ErrNotFound, nothing left to do
+		}
+		results = append(results, p)
+	}
+
+	return results
+}
+
+// ArtworkUpdate records the outcome of processing the artwork from image. On success the
+// thumbnails are made from image now, otherwise the last good ones are kept.
+func ArtworkUpdate(uid string, image string, err error) error {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	podcast_metadata := ds.Collection(datastore.PODCASTS_COL)
+
+	now := util.Timestamp()
+	update := bson.M{"artwork": image, "artworkerror": "", "artworknext": now + ARTWORK_REFRESH}
+	if err != nil {
+		update = bson.M{"artworkerror": err.Error(), "artworknext": now + ARTWORK_RETRY}
+	}
+
+	return podcast_metadata.Update(bson.M{"uid": uid}, bson.M{"$set": update})
+}
@@ -0,0 +1,185 @@
+package artwork
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+
+	_ "image/gif"
+
+	"github.com/mindcastio/mindcastio/backend/feed"
+)
+
+const (
+	MAX_ARTWORK_BYTES int64 = 10 * 1024 * 1024
+	MIN_ARTWORK_SIZE  int   = 128  // px, smaller images are not worth a thumbnail
+	MAX_ARTWORK_SIZE  int   = 4096 // px, larger images are not decoded at all, 64MB as RGBA
+	JPEG_QUALITY      int   = 85
+)
+
+var (
+	ErrArtworkUrl    = errors.New("artwork: invalid url")
+	ErrArtworkFormat = errors.New("artwork: unsupported image format")
+	ErrArtworkSize   = errors.New("artwork: unsupported image dimensions")
+
+	// Sizes of the thumbnails, largest first. The longer side is scaled to the given pixels.
+	Sizes = []Size{
+		{"large", 600},
+		{"medium", 300},
+		{"small", 100},
+	}
+)
+
+type Size struct {
+	Name   string
+	Pixels int
+}
+
+// Key of the thumbnail of a podcast in the blob store
+func Key(uid string, size string) string {
+	return uid + "/" + size
+}
+
+// IsSize is true for the names in Sizes
+func IsSize(name string) bool {
+	for _, s := range Sizes {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Process downloads the artwork at u, validates it and stores a thumbnail in every size for the podcast
+func Process(uid string, u string) error {
+	target, err := url.Parse(u)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return ErrArtworkUrl
+	}
+
+	data, _, err := feed.Download(feed.DefaultFetcher, u, MAX_ARTWORK_BYTES)
+	if err != nil {
+		return err
+	}
+
+	img, format, err := Decode(data)
+	if err != nil {
+		return err
+	}
+
+	thumbnails, err := Thumbnails(img, format)
+	if err != nil {
+		return err
+	}
+
+	for i := range Sizes {
+		err = GetStore().Put(Key(uid, Sizes[i].Name), thumbnails[i])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode checks format and dimensions before decoding the image, a huge image
+// would take a lot of memory. Supported formats are JPEG, PNG and GIF.
+func Decode(data []byte) (image.Image, string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrArtworkFormat
+	}
+	if config.Width < MIN_ARTWORK_SIZE || config.Height < MIN_ARTWORK_SIZE || config.Width > MAX_ARTWORK_SIZE || config.Height > MAX_ARTWORK_SIZE {
+		return nil, "", ErrArtworkSize
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return img, format, nil
+}
+
+// Thumbnails encodes the image in all Sizes, in the same order. PNG images stay PNG
+// to keep their transparency, everything else becomes JPEG.
+func Thumbnails(img image.Image, format string) ([][]byte, error) {
+	thumbnails := make([][]byte, len(Sizes))
+
+	// every size is scaled down from the next larger one, that is a lot less work than from the original
+	src := img
+	for i := range Sizes {
+		thumbnail := resize(src, Sizes[i].Pixels)
+
+		var buf bytes.Buffer
+		var err error
+		if format == "png" {
+			err = png.Encode(&buf, thumbnail)
+		} else {
+			err = jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: JPEG_QUALITY})
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		thumbnails[i] = buf.Bytes()
+		src = thumbnail
+	}
+
+	return thumbnails, nil
+}
+
+// resize scales the image down so that its longer side has size pixels, images are never scaled up.
+// Every pixel is the average of the pixels it covers in the source.
+func resize(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	tw, th := w, h
+	if w >= h && w > size {
+		tw, th = size, h*size/w
+	} else if h > w && h > size {
+		tw, th = w*size/h, size
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0 := b.Min.Y + y*h/th
+		y1 := b.Min.Y + (y+1)*h/th
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < tw; x++ {
+			x0 := b.Min.X + x*w/tw
+			x1 := b.Min.X + (x+1)*w/tw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, bl, a uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r += (uint64)(cr)
+					g += (uint64)(cg)
+					bl += (uint64)(cb)
+					a += (uint64)(ca)
+				}
+			}
+
+			n := (uint64)((y1 - y0) * (x1 - x0))
+			dst.SetRGBA64(x, y, color.RGBA64{(uint16)(r / n), (uint16)(g / n), (uint16)(bl / n), (uint16)(a / n)})
+		}
+	}
+
+	return dst
+}
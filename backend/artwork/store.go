@@ -0,0 +1,128 @@
+package artwork
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mindcastio/mindcastio/backend/environment"
+	"github.com/mindcastio/mindcastio/backend/logger"
+)
+
+var (
+	ErrBlobNotFound = errors.New("artwork: blob not found")
+	ErrInvalidKey   = errors.New("artwork: invalid key")
+
+	_store BlobStore
+)
+
+// BlobStore keeps the thumbnails. The crawler writes and the API reads them,
+// if they run on different machines the store has to be shared.
+type BlobStore interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, time.Time, error) // the data and when it was stored
+	Delete(key string) error
+}
+
+// LocalStore keeps every blob in a file below its root directory
+type LocalStore struct {
+	root string
+}
+
+func Initialize(env *environment.Environment) {
+	logger.Log("artwork.initialize", env.ArtworkDir())
+	_store = NewLocalStore(env.ArtworkDir())
+}
+
+// SetStore replaces the default local filesystem store
+func SetStore(s BlobStore) {
+	_store = s
+}
+
+func GetStore() BlobStore {
+	return _store
+}
+
+func NewLocalStore(root string) *LocalStore {
+	return &LocalStore{root}
+}
+
+func (s *LocalStore) Put(key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, readers never see a partial blob
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".blob")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (s *LocalStore) Get(key string) ([]byte, time.Time, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, time.Time{}, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return data, info.ModTime(), nil
+}
+
+func (s *LocalStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// path maps a key to a file, keys must not point outside of the root
+func (s *LocalStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || clean != "/"+key || strings.HasPrefix(key, ".") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
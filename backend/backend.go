@@ -1,74 +1,126 @@
 package backend
 
 import (
+	"errors"
 	"math"
-	"strconv"
+	"net/url"
+	"path"
 	"strings"
 
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 
 	"github.com/mindcastio/mindcastio/backend/datastore"
+	"github.com/mindcastio/mindcastio/backend/environment"
+	fetch "github.com/mindcastio/mindcastio/backend/feed"
 	"github.com/mindcastio/mindcastio/backend/logger"
 	"github.com/mindcastio/mindcastio/backend/metrics"
 	"github.com/mindcastio/mindcastio/backend/util"
 )
 
-func SubmitPodcastFeed(feed string) error {
+var (
+	ErrFeedExists = errors.New("feed url belongs to another podcast")
+	ErrNoMedia    = errors.New("no audio or video enclosures")
 
-	logger.Log("submit_podcast_feed", feed)
+	mediaExtensions = map[string]bool{
+		".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".oga": true, ".opus": true, ".wav": true, ".flac": true,
+		".mp4": true, ".m4v": true, ".mov": true, ".webm": true,
+	}
+)
 
-	// check if the podcast is already in the index
-	uid := util.UID(feed)
-	idx := IndexLookup(uid)
+// SubmitPodcastFeed adds a feed to the index after checking that it really is a podcast,
+// i.e. a RSS or Atom feed with audio or video enclosures. A HTML page advertising a feed
+// is fine too. The error is only set if the submission could not be stored, or the host
+// could not be asked right now (feed.ErrDeferred).
+func SubmitPodcastFeed(raw string) (*Submission, error) {
 
-	if idx == nil {
-		err := IndexAdd(uid, feed)
-		if err != nil {
+	logger.Log("submit_podcast_feed", raw)
+
+	// http vs. https, www. or tracking parameters don't make a different podcast
+	canonical, err := util.CanonicalUrl(raw)
+	if err != nil {
+		return submission(SUBMISSION_REJECTED, err.Error(), raw, ""), nil
+	}
+	feed, _ := util.NormalizeUrl(raw)
+
+	// check if the podcast is already in the index, maybe under another url
+	idx := indexFind(raw, canonical)
+	if idx != nil {
+		return submission(SUBMISSION_DUPLICATE, "", feed, idx.Uid), nil
+	}
 
-			logger.Error("submit_podcast_feed.error", err, feed)
-			metrics.Error("submit_podcast_feed.error", err.Error(), []string{feed})
+	// fetch and parse it, the url might be a web page pointing to the feed
+	channel, response, err := fetch.Resolve(fetch.DefaultFetcher, feed)
+	if err == fetch.ErrDeferred {
+		return nil, err
+	}
+	if err != nil {
+		return submission(SUBMISSION_REJECTED, err.Error(), feed, ""), nil
+	}
+	if !hasMedia(channel) {
+		return submission(SUBMISSION_REJECTED, ErrNoMedia.Error(), feed, ""), nil
+	}
 
-			return err
+	if response.Url != feed {
+		// discovered or redirected, check again
+		canonical, err = util.CanonicalUrl(response.Url)
+		if err != nil {
+			return submission(SUBMISSION_REJECTED, err.Error(), response.Url, ""), nil
+		}
+		feed, _ = util.NormalizeUrl(response.Url)
+
+		idx = indexFind(response.Url, canonical)
+		if idx != nil {
+			return submission(SUBMISSION_DUPLICATE, "", feed, idx.Uid), nil
 		}
-	} else {
-		logger.Warn("submit_podcast_feed.duplicate", uid, feed)
-		metrics.Warning("submit_podcast_feed.duplicate", "", []string{feed})
 	}
 
-	logger.Log("submit_podcast_feed.done", uid, feed)
-	return nil
-}
+	uid := util.UID(canonical)
+	err = IndexAdd(uid, feed, canonical)
+	if err != nil {
 
-func BulkSubmitPodcastFeed(urls []string) (int, error) {
+		logger.Error("submit_podcast_feed.error", err, feed)
+		metrics.Error("submit_podcast_feed.error", err.Error(), []string{feed})
 
-	logger.Log("bulk_submit_podcast_feed")
+		return nil, err
+	}
 
-	count := 0
-	feed := ""
+	logger.Log("submit_podcast_feed.done", uid, feed)
+	return submission(SUBMISSION_ACCEPTED, "", feed, uid), nil
+}
 
-	for i := range urls {
-		feed = urls[i]
+func submission(status string, reason string, feed string, uid string) *Submission {
+	id, _ := util.UUID()
 
-		// check if the podcast is already in the index
-		uid := util.UID(feed)
-		idx := IndexLookup(uid)
+	switch status {
+	case SUBMISSION_DUPLICATE:
+		logger.Warn("submit_podcast_feed.duplicate", uid, feed)
+		metrics.Warning("submit_podcast_feed.duplicate", "", []string{feed})
+	case SUBMISSION_REJECTED:
+		logger.Warn("submit_podcast_feed.rejected", feed, reason)
+		metrics.Warning("submit_podcast_feed.rejected", reason, []string{feed})
+	}
 
-		if idx == nil {
-			err := IndexAdd(uid, feed)
-			if err != nil {
+	return &Submission{id, status, reason, feed, uid}
+}
 
-				logger.Error("bulk_submit_podcast_feed.error", err, feed)
-				metrics.Error("bulk_submit_podcast_feed.error", err.Error(), []string{feed})
+// hasMedia is true if at least one item has an audio or video enclosure
+func hasMedia(channel *fetch.Channel) bool {
+	for i := range channel.Item {
+		for _, e := range channel.Item[i].Enclosure {
+			t := strings.ToLower(e.Type)
+			if strings.HasPrefix(t, "audio/") || strings.HasPrefix(t, "video/") {
+				return true
+			}
 
-				return 0, err
-			} else {
-				count++
+			// the type is often missing or wrong, look at the file extension then
+			u, err := url.Parse(e.URL)
+			if err == nil && mediaExtensions[strings.ToLower(path.Ext(u.Path))] {
+				return true
 			}
 		}
 	}
-
-	logger.Log("bulk_submit_podcast_feed.done", strconv.FormatInt((int64)(count), 10))
-	return count, nil
+	return false
 }
 
 func IndexLookup(uid string) *PodcastIndex {
@@ -88,7 +140,57 @@ func IndexLookup(uid string) *PodcastIndex {
 	}
 }
 
-func IndexAdd(uid string, url string) error {
+// indexFind looks up a podcast by its canonical feed url or one of its aliases. Podcasts added
+// before the urls were canonicalized are found by the uid or feed of the raw url.
+func indexFind(raw string, canonical string) *PodcastIndex {
+	idx := IndexLookupCanonical(canonical)
+	if idx == nil {
+		idx = IndexLookup(util.UID(raw))
+	}
+	if idx == nil {
+		idx = IndexLookupFeed(raw)
+	}
+	return idx
+}
+
+// IndexLookupCanonical finds a podcast by its canonical feed url or one of its aliases
+func IndexLookupCanonical(canonical string) *PodcastIndex {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	i := PodcastIndex{}
+	main_index.Find(bson.M{"$or": []bson.M{{"canonical": canonical}, {"aliases": canonical}}}).One(&i)
+
+	if i.Feed == "" {
+		return nil
+	} else {
+		return &i
+	}
+}
+
+// IndexLookupFeed finds a podcast by its current feed url, it differs from
+// the uid once the feed was migrated
+func IndexLookupFeed(feed string) *PodcastIndex {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	i := PodcastIndex{}
+	main_index.Find(bson.M{"feed": feed}).One(&i)
+
+	if i.Feed == "" {
+		return nil
+	} else {
+		return &i
+	}
+}
+
+func IndexAdd(uid string, url string, canonical string) error {
 	ds := datastore.GetDataStore()
 	defer ds.Close()
 
@@ -97,7 +199,7 @@ func IndexAdd(uid string, url string) error {
 	// add some random element to the first update point in time
 	next := util.IncT(util.Timestamp(), util.Random(FIRST_UPDATE_RATE))
 
-	i := PodcastIndex{uid, url, DEFAULT_UPDATE_RATE, next, 0, 0, util.Timestamp(), 0}
+	i := PodcastIndex{uid, url, DEFAULT_UPDATE_RATE, next, 0, 0, util.Timestamp(), 0, "", "", 0, nil, false, 0, false, canonical, nil, "", "", 0}
 	return main_index.Insert(&i)
 }
 
@@ -114,17 +216,93 @@ func IndexUpdate(uid string) error {
 	if i.Feed == "" || err != nil {
 		return err
 	} else {
-		i.Updated = util.Timestamp()
-		i.Next = util.IncT(i.Next, i.UpdateRate+util.RandomPlusMinus(15)) // FIXME adjust next in case there was a downtime > i.UpdateRate
-		i.Errors = 0                                                      // reset in case there was an erro
+		now := util.Timestamp()
+
+		// adjust the update rate to the publishing cadence of the podcast, unless it was set by an admin
+		if !i.FixedRate {
+			change := updateRate(uid, now)
+			if change.Rate != i.UpdateRate {
+				i.UpdateRate = change.Rate
+
+				// an admin might have fixed the rate in the meantime
+				rate := bson.M{
+					"$set":  bson.M{"updaterate": change.Rate},
+					"$push": bson.M{"ratehistory": bson.M{"$each": []RateChange{*change}, "$slice": -RATE_HISTORY}},
+				}
+				err = main_index.Update(bson.M{"uid": uid, "fixedrate": bson.M{"$ne": true}}, rate)
+				if err != nil && err != mgo.ErrNotFound {
+					return err
+				}
+			}
+		}
+
+		// don't schedule into the past in case there was a downtime > i.UpdateRate
+		next := i.Next
+		if next < now {
+			next = now
+		}
 
-		// update the DB
-		err = main_index.Update(bson.M{"uid": uid}, &i)
+		// only the fields computed here, others are written concurrently, e.g. the lease
+		update := bson.M{
+			"$set": bson.M{
+				"updated":      now,
+				"next":         util.IncT(next, i.UpdateRate+util.RandomPlusMinus(15)),
+				"errors":       0, // reset in case there was an error
+				"failingsince": 0,
+				"dead":         false,
+			},
+			"$inc": bson.M{"n": 1},
+		}
+		err = main_index.Update(bson.M{"uid": uid}, update)
 	}
 
 	return err
 }
 
+func IndexUpdateValidators(uid string, etag string, lastModified string, size int64) error {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	return main_index.Update(bson.M{"uid": uid}, bson.M{"$set": bson.M{"etag": etag, "lastmodified": lastModified, "size": size}})
+}
+
+// IndexMigrateFeed moves a podcast to a new feed url, the uid stays the same.
+// It fails if the new url already belongs to another podcast.
+func IndexMigrateFeed(uid string, raw string) error {
+
+	canonical, err := util.CanonicalUrl(raw)
+	if err != nil {
+		return err
+	}
+	feed, _ := util.NormalizeUrl(raw)
+
+	idx := indexFind(raw, canonical)
+	if idx != nil && idx.Uid != uid {
+		return ErrFeedExists
+	}
+
+	current := IndexLookup(uid)
+	if current == nil {
+		return nil
+	}
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	// the old url is kept as an alias
+	update := bson.M{"$set": bson.M{"feed": feed, "canonical": canonical}}
+	if current.Canonical != "" && current.Canonical != canonical {
+		update["$addToSet"] = bson.M{"aliases": current.Canonical}
+	}
+
+	return main_index.Update(bson.M{"uid": uid}, update)
+}
+
 func IndexBackoff(uid string) (bool, error) {
 
 	ds := datastore.GetDataStore()
@@ -139,25 +317,53 @@ func IndexBackoff(uid string) (bool, error) {
 	if i.Feed == "" || err != nil {
 		return suspended, err
 	} else {
+		env := environment.GetEnvironment()
+
 		i.Updated = util.Timestamp()
+		if i.Errors == 0 || i.FailingSince == 0 {
+			i.FailingSince = i.Updated
+		}
 		i.Errors++
 
 		if i.Errors > MAX_ERRORS {
-			// just disable the UID by using a LAAAARGE next time
-			i.Next = math.MaxInt64
 			suspended = true
+
+			if (int)((i.Updated-i.FailingSince)/60) > env.CrawlerDeadAfter() {
+				// failing for too long, give up by using a LAAAARGE next time
+				i.Next = math.MaxInt64
+				i.Dead = true
+
+				logger.Warn("index_backoff.dead", uid, i.Feed, util.TimestampToUTC(i.FailingSince))
+				metrics.Count("index.podcasts.dead", 1)
+			} else {
+				// hosts come back after outages, probe the feed once in a while
+				i.Next = util.IncT(i.Updated, env.CrawlerProbeRate())
+			}
 		} else {
 			// + 10, 100, 1000, 10000 min ...
 			i.Next = util.IncT(i.Updated, (int)(math.Pow(10, (float64)(i.Errors))))
 		}
 
-		// update the DB
-		err = main_index.Update(bson.M{"uid": uid}, &i)
+		// update the DB, only the fields computed here
+		update := bson.M{"updated": i.Updated, "failingsince": i.FailingSince, "errors": i.Errors, "next": i.Next, "dead": i.Dead}
+		err = main_index.Update(bson.M{"uid": uid}, bson.M{"$set": update})
 	}
 
 	return suspended, err
 }
 
+// IndexDefer reschedules a podcast in delay min. without counting an error, the crawl
+// was skipped to be polite to the host
+func IndexDefer(uid string, delay int) error {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	return main_index.Update(bson.M{"uid": uid}, bson.M{"$set": bson.M{"next": util.IncT(util.Timestamp(), delay)}})
+}
+
 func PodcastLookup(uid string) *PodcastMetadata {
 
 	ds := datastore.GetDataStore()
@@ -175,7 +381,7 @@ func PodcastLookup(uid string) *PodcastMetadata {
 	}
 }
 
-func PodcastLookupLatestEpisode(uid string) *EpisodeMetadata {
+func PodcastLookupLatestEpisode(uid string, removed bool) *EpisodeMetadata {
 
 	ds := datastore.GetDataStore()
 	defer ds.Close()
@@ -183,12 +389,12 @@ func PodcastLookupLatestEpisode(uid string) *EpisodeMetadata {
 	episodes_metadata := ds.Collection(datastore.EPISODES_COL)
 
 	episode := EpisodeMetadata{}
-	episodes_metadata.Find(bson.M{"podcastuid": uid}).Sort("-published").One(&episode)
+	episodes_metadata.Find(episodesQuery(uid, removed)).Sort("-published").One(&episode)
 
 	return &episode
 }
 
-func PodcastLookupAllEpisodes(uid string) []*EpisodeMetadata {
+func PodcastLookupAllEpisodes(uid string, removed bool) []*EpisodeMetadata {
 
 	ds := datastore.GetDataStore()
 	defer ds.Close()
@@ -196,11 +402,19 @@ func PodcastLookupAllEpisodes(uid string) []*EpisodeMetadata {
 	episodes_metadata := ds.Collection(datastore.EPISODES_COL)
 
 	episodes := []*EpisodeMetadata{}
-	episodes_metadata.Find(bson.M{"podcastuid": uid}).Sort("-published").All(&episodes)
+	episodes_metadata.Find(episodesQuery(uid, removed)).Sort("-published").All(&episodes)
 
 	return episodes
 }
 
+func episodesQuery(uid string, removed bool) bson.M {
+	if removed {
+		return bson.M{"podcastuid": uid}
+	}
+	// older records don't have the removed attribute at all
+	return bson.M{"podcastuid": uid, "removed": bson.M{"$in": []interface{}{0, nil}}}
+}
+
 func EpisodeLookup(uid string) *EpisodeMetadata {
 
 	ds := datastore.GetDataStore()
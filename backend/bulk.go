@@ -0,0 +1,209 @@
+package backend
+
+import (
+	"strconv"
+	"strings"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/backend/datastore"
+	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/metrics"
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+type bulkEntry struct {
+	raw       string
+	feed      string
+	canonical string
+	uid       string
+}
+
+// BulkSubmitPodcastFeed adds feeds to the index without checking them first, the crawler
+// sorts out the bad ones. Empty urls are skipped, everything else gets an outcome, in the
+// order of the urls. A failed insert does not stop the rest of the list, an error is only
+// returned if the index could not be queried at all.
+func BulkSubmitPodcastFeed(urls []string) ([]BulkOutcome, error) {
+
+	logger.Log("bulk_submit_podcast_feed")
+
+	outcomes := make([]BulkOutcome, 0, len(urls))
+	entries := make([]*bulkEntry, 0, len(urls))
+
+	for i := range urls {
+		raw := strings.TrimSpace(urls[i])
+		if raw == "" {
+			continue
+		}
+
+		canonical, err := util.CanonicalUrl(raw)
+		if err != nil {
+			logger.Warn("bulk_submit_podcast_feed.invalid", raw)
+			outcomes = append(outcomes, BulkOutcome{raw, BULK_INVALID, "", err.Error()})
+			entries = append(entries, nil)
+			continue
+		}
+		feed, _ := util.NormalizeUrl(raw)
+
+		outcomes = append(outcomes, BulkOutcome{raw, "", "", ""})
+		entries = append(entries, &bulkEntry{raw, feed, canonical, util.UID(canonical)})
+	}
+
+	known, err := bulkLookup(entries)
+	if err != nil {
+		logger.Error("bulk_submit_podcast_feed.error", err)
+		metrics.Error("bulk_submit_podcast_feed.error", err.Error(), nil)
+		return nil, err
+	}
+
+	for i, e := range entries {
+		if e == nil {
+			continue
+		}
+
+		// already in the index, or earlier in the list
+		uid, found := known[e.canonical]
+		if !found {
+			uid, found = known[e.raw]
+		}
+		if !found {
+			uid, found = known[util.UID(e.raw)]
+		}
+		if found {
+			outcomes[i].Status = BULK_DUPLICATE
+			outcomes[i].Uid = uid
+			continue
+		}
+
+		err := IndexAdd(e.uid, e.feed, e.canonical)
+		if err != nil {
+			if mgo.IsDup(err) {
+				// added concurrently
+				outcomes[i].Status = BULK_DUPLICATE
+				outcomes[i].Uid = e.uid
+				continue
+			}
+
+			logger.Error("bulk_submit_podcast_feed.error", err, e.feed)
+			metrics.Error("bulk_submit_podcast_feed.error", err.Error(), []string{e.feed})
+
+			outcomes[i].Status = BULK_ERROR
+			outcomes[i].Reason = err.Error()
+			continue
+		}
+
+		outcomes[i].Status = BULK_ADDED
+		outcomes[i].Uid = e.uid
+		known[e.canonical] = e.uid
+	}
+
+	logger.Log("bulk_submit_podcast_feed.done", BulkSummary(outcomes))
+	return outcomes, nil
+}
+
+// IndexResolveUids maps feed urls to the uids of the podcasts they belong to, with one query.
+// Urls that are not in the index are missing from the result.
+func IndexResolveUids(urls []string) (map[string]string, error) {
+
+	entries := make([]*bulkEntry, 0, len(urls))
+	for _, raw := range urls {
+		canonical, err := util.CanonicalUrl(raw)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, &bulkEntry{raw, "", canonical, ""})
+	}
+
+	known, err := bulkLookup(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	uids := make(map[string]string, len(entries))
+	for _, e := range entries {
+		uid, found := known[e.canonical]
+		if !found {
+			uid, found = known[e.raw]
+		}
+		if !found {
+			uid, found = known[util.UID(e.raw)]
+		}
+		if found {
+			uids[e.raw] = uid
+		}
+	}
+	return uids, nil
+}
+
+// bulkLookup finds the podcasts already in the index with one query, the result maps
+// canonical urls, aliases, feeds and uids to the uid of the podcast
+func bulkLookup(entries []*bulkEntry) (map[string]string, error) {
+
+	known := make(map[string]string)
+
+	canonicals := make([]string, 0, len(entries))
+	raws := make([]string, 0, len(entries))
+	uids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e != nil {
+			canonicals = append(canonicals, e.canonical)
+			raws = append(raws, e.raw)
+			uids = append(uids, util.UID(e.raw))
+		}
+	}
+	if len(canonicals) == 0 {
+		return known, nil
+	}
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	// podcasts added before the urls were canonicalized are found by the uid or feed of the raw url
+	q := bson.M{"$or": []bson.M{
+		{"canonical": bson.M{"$in": canonicals}},
+		{"aliases": bson.M{"$in": canonicals}},
+		{"feed": bson.M{"$in": raws}},
+		{"uid": bson.M{"$in": uids}},
+	}}
+
+	results := []PodcastIndex{}
+	err := main_index.Find(q).Select(bson.M{"uid": 1, "feed": 1, "canonical": 1, "aliases": 1}).All(&results)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		known[results[i].Uid] = results[i].Uid
+		known[results[i].Feed] = results[i].Uid
+		if results[i].Canonical != "" {
+			known[results[i].Canonical] = results[i].Uid
+		}
+		for _, alias := range results[i].Aliases {
+			known[alias] = results[i].Uid
+		}
+	}
+
+	return known, nil
+}
+
+// BulkCount counts the outcomes with the given status
+func BulkCount(outcomes []BulkOutcome, status string) int {
+	n := 0
+	for i := range outcomes {
+		if outcomes[i].Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// BulkSummary is for logging, e.g. "added=3 duplicate=10 invalid=0 error=0"
+func BulkSummary(outcomes []BulkOutcome) string {
+	return "added=" + strconv.Itoa(BulkCount(outcomes, BULK_ADDED)) +
+		" duplicate=" + strconv.Itoa(BulkCount(outcomes, BULK_DUPLICATE)) +
+		" invalid=" + strconv.Itoa(BulkCount(outcomes, BULK_INVALID)) +
+		" error=" + strconv.Itoa(BulkCount(outcomes, BULK_ERROR))
+}
@@ -16,6 +16,11 @@ const (
 	EPISODES_COL    string = "episodes"
 	SEARCH_TERM_COM string = "search_term"
 	KEYWORDS_COL    string = "keywords"
+
+	PODCAST_CHANGES_COL string = "podcast_changes"
+	CRAWL_HISTORY_COL   string = "crawl_history"
+	ADMIN_AUDIT_COL     string = "admin_audit"
+	SUBMIT_JOBS_COL     string = "submit_jobs"
 )
 
 var _session *mgo.Session
@@ -18,6 +18,26 @@ func createIndex() {
 	if err != nil {
 		logger.Error("backend.datastore.create_index", err, "")
 	}
+	// main_index.feed
+	err = main_index.EnsureIndex(mgo.Index{Key: []string{"feed"}, Unique: false, DropDups: false, Background: true, Sparse: true})
+	if err != nil {
+		logger.Error("backend.datastore.create_index", err, "")
+	}
+	// main_index.canonical
+	err = main_index.EnsureIndex(mgo.Index{Key: []string{"canonical"}, Unique: false, DropDups: false, Background: true, Sparse: true})
+	if err != nil {
+		logger.Error("backend.datastore.create_index", err, "")
+	}
+	// main_index.aliases
+	err = main_index.EnsureIndex(mgo.Index{Key: []string{"aliases"}, Unique: false, DropDups: false, Background: true, Sparse: true})
+	if err != nil {
+		logger.Error("backend.datastore.create_index", err, "")
+	}
+	// main_index.contentkey
+	err = main_index.EnsureIndex(mgo.Index{Key: []string{"contentkey"}, Unique: false, DropDups: false, Background: true, Sparse: true})
+	if err != nil {
+		logger.Error("backend.datastore.create_index", err, "")
+	}
 	// main_index.next
 	err = main_index.EnsureIndex(mgo.Index{Key: []string{"next"}, Unique: false, DropDups: true, Background: true, Sparse: true})
 	if err != nil {
@@ -51,6 +71,48 @@ func createIndex() {
 	if err != nil {
 		logger.Error("backend.datastore.create_index", err, "")
 	}
+	// podcast_metadata.artworknext, not sparse as podcasts without it are due
+	err = podcast_metadata.EnsureIndex(mgo.Index{Key: []string{"artworknext"}, Unique: false, DropDups: false, Background: true, Sparse: false})
+	if err != nil {
+		logger.Error("backend.datastore.create_index", err, "")
+	}
+
+	// podcast metadata change log
+	podcast_changes := ds.Collection(PODCAST_CHANGES_COL)
+	// podcast_changes.uid
+	err = podcast_changes.EnsureIndex(mgo.Index{Key: []string{"uid", "-created"}, Unique: false, DropDups: false, Background: true, Sparse: true})
+	if err != nil {
+		logger.Error("backend.datastore.create_index", err, "")
+	}
+
+	// crawl history
+	crawl_history := ds.Collection(CRAWL_HISTORY_COL)
+	// crawl_history.uid
+	err = crawl_history.EnsureIndex(mgo.Index{Key: []string{"uid", "-created"}, Unique: false, DropDups: false, Background: true, Sparse: true})
+	if err != nil {
+		logger.Error("backend.datastore.create_index", err, "")
+	}
+
+	// admin audit log
+	admin_audit := ds.Collection(ADMIN_AUDIT_COL)
+	// admin_audit.uid
+	err = admin_audit.EnsureIndex(mgo.Index{Key: []string{"uid", "-created"}, Unique: false, DropDups: false, Background: true, Sparse: true})
+	if err != nil {
+		logger.Error("backend.datastore.create_index", err, "")
+	}
+
+	// submit jobs
+	submit_jobs := ds.Collection(SUBMIT_JOBS_COL)
+	// submit_jobs.id
+	err = submit_jobs.EnsureIndex(mgo.Index{Key: []string{"id"}, Unique: true, DropDups: true, Background: true, Sparse: true})
+	if err != nil {
+		logger.Error("backend.datastore.create_index", err, "")
+	}
+	// submit_jobs.status
+	err = submit_jobs.EnsureIndex(mgo.Index{Key: []string{"status", "created"}, Unique: false, DropDups: false, Background: true, Sparse: true})
+	if err != nil {
+		logger.Error("backend.datastore.create_index", err, "")
+	}
 
 	// episode metadata
 	episodes_metadata := ds.Collection(EPISODES_COL)
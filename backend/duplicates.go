@@ -0,0 +1,98 @@
+package backend
+
+import (
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/backend/datastore"
+	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/metrics"
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+func IndexUpdateContentKey(uid string, key string) error {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	return main_index.Update(bson.M{"uid": uid}, bson.M{"$set": bson.M{"contentkey": key}})
+}
+
+// IndexFindDuplicate looks for another podcast with the same content key that also
+// has one of the given enclosures, i.e. the same show under a different feed url
+func IndexFindDuplicate(uid string, key string, enclosures []string) *PodcastIndex {
+
+	if key == "" || len(enclosures) == 0 {
+		return nil
+	}
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+	episodes_metadata := ds.Collection(datastore.EPISODES_COL)
+
+	candidates := []PodcastIndex{}
+	main_index.Find(bson.M{"contentkey": key, "uid": bson.M{"$ne": uid}, "dead": bson.M{"$ne": true}}).All(&candidates)
+
+	for i := range candidates {
+		n, _ := episodes_metadata.Find(bson.M{"podcastuid": candidates[i].Uid, "asseturl": bson.M{"$in": enclosures}}).Count()
+		if n > 0 {
+			return &candidates[i]
+		}
+	}
+
+	return nil
+}
+
+// IndexMerge merges the podcast dup into the podcast uid. The urls of the duplicate are kept as
+// aliases and its index entry is removed. Its metadata and episodes stay but are marked,
+// so that the indexer removes them from search.
+func IndexMerge(uid string, dup string) error {
+
+	idx := IndexLookup(uid)
+	d := IndexLookup(dup)
+	if idx == nil || d == nil {
+		return nil
+	}
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+	podcast_metadata := ds.Collection(datastore.PODCASTS_COL)
+	episodes_metadata := ds.Collection(datastore.EPISODES_COL)
+	podcast_changes := ds.Collection(datastore.PODCAST_CHANGES_COL)
+
+	aliases := []string{}
+	if d.Canonical != "" && d.Canonical != idx.Canonical {
+		aliases = append(aliases, d.Canonical)
+	}
+	for _, a := range d.Aliases {
+		if a != idx.Canonical {
+			aliases = append(aliases, a)
+		}
+	}
+
+	err := main_index.Update(bson.M{"uid": uid}, bson.M{"$addToSet": bson.M{"aliases": bson.M{"$each": aliases}}})
+	if err != nil {
+		return err
+	}
+
+	err = main_index.Remove(bson.M{"uid": dup})
+	if err != nil {
+		return err
+	}
+
+	now := util.Timestamp()
+
+	podcast_metadata.Update(bson.M{"uid": dup}, bson.M{"$set": bson.M{"mergedinto": uid, "version": 0, "updated": now}})
+	episodes_metadata.UpdateAll(bson.M{"podcastuid": dup, "removed": bson.M{"$in": []interface{}{0, nil}}}, bson.M{"$set": bson.M{"removed": now, "version": 0, "updated": now}})
+	podcast_changes.Insert(&PodcastChange{uid, "merged", d.Feed, dup, now})
+
+	logger.Log("index_merge", uid, dup, d.Feed)
+	metrics.Count("index.podcasts.merged", 1)
+
+	return nil
+}
@@ -14,6 +14,23 @@ const (
 	BACKEND_HOSTS          string = "BACKEND_HOSTS"
 	BACKEND_MESSAGING_PORT string = "BACKEND_MESSAGING_PORT"
 	BACKEND_SEARCH_PORT    string = "BACKEND_SEARCH_PORT"
+	CRAWLER_WORKERS        string = "CRAWLER_WORKERS"
+	CRAWLER_HOST_WORKERS   string = "CRAWLER_HOST_WORKERS"
+	CRAWLER_DEADLINE       string = "CRAWLER_DEADLINE"
+	CRAWLER_MIN_RATE       string = "CRAWLER_MIN_RATE"
+	CRAWLER_MAX_RATE       string = "CRAWLER_MAX_RATE"
+	CRAWLER_PROBE_RATE     string = "CRAWLER_PROBE_RATE"
+	CRAWLER_DEAD_AFTER     string = "CRAWLER_DEAD_AFTER"
+	CRAWLER_USER_AGENT     string = "CRAWLER_USER_AGENT"
+	CRAWLER_HOST_SPACING   string = "CRAWLER_HOST_SPACING"
+	CRAWLER_ROBOTS         string = "CRAWLER_ROBOTS"
+	CRAWLER_MAX_FEED_SIZE  string = "CRAWLER_MAX_FEED_SIZE"
+	CRAWLER_MAX_ITEMS      string = "CRAWLER_MAX_ITEMS"
+	CRAWLER_MAX_FIELD      string = "CRAWLER_MAX_FIELD"
+	ARTWORK_DIR            string = "ARTWORK_DIR"
+	ADMIN_USER             string = "ADMIN_USER"
+	ADMIN_PASSWORD         string = "ADMIN_PASSWORD"
+	SHUTDOWN_GRACE         string = "SHUTDOWN_GRACE"
 
 	// defaults
 	DEFAULT_LISTEN_PORT            string = ":42001"
@@ -22,6 +39,21 @@ const (
 	DEFAULT_BACKEND_HOSTS          string = "127.0.0.1"
 	DEFAULT_BACKEND_MESSAGING_PORT string = "4222"
 	DEFAULT_BACKEND_SEARCH_PORT    string = "9200"
+	DEFAULT_CRAWLER_WORKERS        int    = 10
+	DEFAULT_CRAWLER_HOST_WORKERS   int    = 2
+	DEFAULT_CRAWLER_DEADLINE       int    = 50     // sec, must be shorter than the crawler schedule
+	DEFAULT_CRAWLER_MIN_RATE       int    = 60     // min., i.e. hourly
+	DEFAULT_CRAWLER_MAX_RATE       int    = 10080  // min., i.e. weekly
+	DEFAULT_CRAWLER_PROBE_RATE     int    = 43200  // min., suspended feeds are probed monthly
+	DEFAULT_CRAWLER_DEAD_AFTER     int    = 259200 // min., a feed failing for 180 days is dead
+	DEFAULT_CRAWLER_USER_AGENT     string = ""
+	DEFAULT_CRAWLER_HOST_SPACING   int    = 2                // sec between two requests to the same host
+	DEFAULT_CRAWLER_MAX_FEED_SIZE  int    = 20 * 1024 * 1024 // bytes
+	DEFAULT_CRAWLER_MAX_ITEMS      int    = 5000             // items after this are ignored
+	DEFAULT_CRAWLER_MAX_FIELD      int    = 64 * 1024        // bytes, longer text fields are cut
+	DEFAULT_ARTWORK_DIR            string = "/var/lib/mindcastio/artwork"
+	DEFAULT_ADMIN_USER             string = "admin"
+	DEFAULT_SHUTDOWN_GRACE         int    = 30 // sec, how long work in progress may take on shutdown
 )
 
 var _environment *Environment
@@ -34,6 +66,23 @@ type Environment struct {
 	backendServiceHosts  []string
 	messagingServicePort string
 	searchServicePort    string
+	crawlerWorkers       int
+	crawlerHostWorkers   int
+	crawlerDeadline      int
+	crawlerMinRate       int
+	crawlerMaxRate       int
+	crawlerProbeRate     int
+	crawlerDeadAfter     int
+	crawlerUserAgent     string
+	crawlerHostSpacing   int
+	crawlerRobots        bool
+	crawlerMaxFeedSize   int
+	crawlerMaxItems      int
+	crawlerMaxField      int
+	artworkDir           string
+	adminUser            string
+	adminPassword        string
+	shutdownGrace        int
 }
 
 func (e *Environment) ListenPort() string {
@@ -64,6 +113,77 @@ func (e *Environment) SearchServicePort() string {
 	return e.searchServicePort
 }
 
+func (e *Environment) CrawlerWorkers() int {
+	return e.crawlerWorkers
+}
+
+func (e *Environment) CrawlerHostWorkers() int {
+	return e.crawlerHostWorkers
+}
+
+func (e *Environment) CrawlerDeadline() int {
+	return e.crawlerDeadline
+}
+
+func (e *Environment) CrawlerMinRate() int {
+	return e.crawlerMinRate
+}
+
+func (e *Environment) CrawlerMaxRate() int {
+	return e.crawlerMaxRate
+}
+
+func (e *Environment) CrawlerProbeRate() int {
+	return e.crawlerProbeRate
+}
+
+func (e *Environment) CrawlerDeadAfter() int {
+	return e.crawlerDeadAfter
+}
+
+// CrawlerUserAgent is empty unless configured, the feed package has the default
+func (e *Environment) CrawlerUserAgent() string {
+	return e.crawlerUserAgent
+}
+
+func (e *Environment) CrawlerHostSpacing() int {
+	return e.crawlerHostSpacing
+}
+
+func (e *Environment) CrawlerRobots() bool {
+	return e.crawlerRobots
+}
+
+func (e *Environment) CrawlerMaxFeedSize() int {
+	return e.crawlerMaxFeedSize
+}
+
+func (e *Environment) CrawlerMaxItems() int {
+	return e.crawlerMaxItems
+}
+
+func (e *Environment) CrawlerMaxField() int {
+	return e.crawlerMaxField
+}
+
+// ArtworkDir is the root of the local thumbnail store
+func (e *Environment) ArtworkDir() string {
+	return e.artworkDir
+}
+
+func (e *Environment) AdminUser() string {
+	return e.adminUser
+}
+
+// AdminPassword is empty if the admin API is disabled
+func (e *Environment) AdminPassword() string {
+	return e.adminPassword
+}
+
+func (e *Environment) ShutdownGrace() int {
+	return e.shutdownGrace
+}
+
 func (e *Environment) MessagingServiceUrls() []string {
 	u := make([]string, len(e.backendServiceHosts))
 	for i := range e.backendServiceHosts {
@@ -73,7 +193,7 @@ func (e *Environment) MessagingServiceUrls() []string {
 }
 
 func (e *Environment) SearchServiceUrl() string {
-	return strings.Join([]string{"http://", e.backendServiceHosts[0], ":", e.searchServicePort,"/"}, "")
+	return strings.Join([]string{"http://", e.backendServiceHosts[0], ":", e.searchServicePort, "/"}, "")
 }
 
 func GetEnvironment() *Environment {
@@ -86,6 +206,23 @@ func GetEnvironment() *Environment {
 			getEnvOrDefaultN(BACKEND_HOSTS, DEFAULT_BACKEND_HOSTS),
 			getEnvOrDefault(BACKEND_MESSAGING_PORT, DEFAULT_BACKEND_MESSAGING_PORT),
 			getEnvOrDefault(BACKEND_SEARCH_PORT, DEFAULT_BACKEND_SEARCH_PORT),
+			getEnvOrDefaultInt(CRAWLER_WORKERS, DEFAULT_CRAWLER_WORKERS),
+			getEnvOrDefaultInt(CRAWLER_HOST_WORKERS, DEFAULT_CRAWLER_HOST_WORKERS),
+			getEnvOrDefaultInt(CRAWLER_DEADLINE, DEFAULT_CRAWLER_DEADLINE),
+			getEnvOrDefaultInt(CRAWLER_MIN_RATE, DEFAULT_CRAWLER_MIN_RATE),
+			getEnvOrDefaultInt(CRAWLER_MAX_RATE, DEFAULT_CRAWLER_MAX_RATE),
+			getEnvOrDefaultInt(CRAWLER_PROBE_RATE, DEFAULT_CRAWLER_PROBE_RATE),
+			getEnvOrDefaultInt(CRAWLER_DEAD_AFTER, DEFAULT_CRAWLER_DEAD_AFTER),
+			getEnvOrDefault(CRAWLER_USER_AGENT, DEFAULT_CRAWLER_USER_AGENT),
+			getEnvOrDefaultInt(CRAWLER_HOST_SPACING, DEFAULT_CRAWLER_HOST_SPACING),
+			getEnvOrDefaultBool(CRAWLER_ROBOTS, false),
+			getEnvOrDefaultInt(CRAWLER_MAX_FEED_SIZE, DEFAULT_CRAWLER_MAX_FEED_SIZE),
+			getEnvOrDefaultInt(CRAWLER_MAX_ITEMS, DEFAULT_CRAWLER_MAX_ITEMS),
+			getEnvOrDefaultInt(CRAWLER_MAX_FIELD, DEFAULT_CRAWLER_MAX_FIELD),
+			getEnvOrDefault(ARTWORK_DIR, DEFAULT_ARTWORK_DIR),
+			getEnvOrDefault(ADMIN_USER, DEFAULT_ADMIN_USER),
+			os.Getenv(ADMIN_PASSWORD),
+			getEnvOrDefaultInt(SHUTDOWN_GRACE, DEFAULT_SHUTDOWN_GRACE),
 		}
 		_environment = &e
 	}
@@ -113,6 +250,19 @@ func getEnvOrDefaultBool(env string, defaultValue bool) bool {
 	}
 }
 
+func getEnvOrDefaultInt(env string, defaultValue int) int {
+	envVar := os.Getenv(env)
+	if envVar == "" {
+		return defaultValue
+	} else {
+		i, err := strconv.Atoi(envVar)
+		if err != nil || i < 1 {
+			return defaultValue
+		}
+		return i
+	}
+}
+
 func getEnvOrDefaultN(env string, defaultValue string) []string {
 	envVar := os.Getenv(env)
 	if envVar == "" {
@@ -0,0 +1,64 @@
+package backend
+
+import (
+	"regexp"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/backend/datastore"
+	fetch "github.com/mindcastio/mindcastio/backend/feed"
+)
+
+// IndexExport returns the podcasts as OPML outlines, optionally only those with a language
+// (prefix, e.g. "en" matches "en-us") or an itunes category. Without a filter, feeds that
+// were never crawled successfully are exported too, with their url as the title.
+func IndexExport(language string, tag string) ([]fetch.Outline, error) {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	podcast_metadata := ds.Collection(datastore.PODCASTS_COL)
+
+	q := bson.M{"mergedinto": bson.M{"$in": []interface{}{"", nil}}}
+	if language != "" {
+		q["language"] = bson.RegEx{"^" + regexp.QuoteMeta(language), "i"}
+	}
+	if tag != "" {
+		q["tags"] = bson.RegEx{regexp.QuoteMeta(tag), "i"}
+	}
+
+	outlines := make([]fetch.Outline, 0)
+	exported := make(map[string]bool)
+
+	p := PodcastMetadata{}
+	iter := podcast_metadata.Find(q).Select(bson.M{"uid": 1, "title": 1, "url": 1, "feed": 1}).Sort("title").Iter()
+	for iter.Next(&p) {
+		outlines = append(outlines, fetch.Outline{Text: p.Title, Title: p.Title, Type: "rss", XmlUrl: p.Feed, HtmlUrl: p.Url})
+		exported[p.Uid] = true
+		p = PodcastMetadata{}
+	}
+	err := iter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if language != "" || tag != "" {
+		return outlines, nil
+	}
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	idx := PodcastIndex{}
+	iter = main_index.Find(nil).Select(bson.M{"uid": 1, "feed": 1}).Sort("created").Iter()
+	for iter.Next(&idx) {
+		if !exported[idx.Uid] {
+			outlines = append(outlines, fetch.Outline{Text: idx.Feed, Type: "rss", XmlUrl: idx.Feed})
+		}
+	}
+	err = iter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return outlines, nil
+}
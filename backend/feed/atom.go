@@ -0,0 +1,156 @@
+package feed
+
+import (
+	"strings"
+	"time"
+)
+
+// Atom 1.0 document, see RFC 4287
+type atomFeed struct {
+	Id       string      `xml:"id"`
+	Title    atomText    `xml:"title"`
+	Subtitle atomText    `xml:"subtitle"`
+	Updated  string      `xml:"updated"`
+	Language string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Link     []atomLink  `xml:"link"`
+	Logo     string      `xml:"logo"`
+	Icon     string      `xml:"icon"`
+	Author   atomPerson  `xml:"author"`
+	Entry    []atomEntry `xml:"entry"`
+
+	// iTunes extensions are sometimes used with Atom as well
+	ItunesSubtitle string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd subtitle"`
+	ItunesOwner    ItunesOwner `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd owner"`
+}
+
+type atomEntry struct {
+	Id        string     `xml:"id"`
+	Title     atomText   `xml:"title"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Link      []atomLink `xml:"link"`
+	Summary   atomText   `xml:"summary"`
+	Content   atomText   `xml:"content"`
+	Author    atomPerson `xml:"author"`
+	Category  []atomTerm `xml:"category"`
+	Duration  string     `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length int    `xml:"length,attr"`
+}
+
+type atomPerson struct {
+	Name  string `xml:"name"`
+	Email string `xml:"email"`
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+// atomText is a text construct, either text, html or xhtml
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Body  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t *atomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Body)
+}
+
+// atomToChannel maps an Atom feed onto the RSS Channel model
+func atomToChannel(a *atomFeed) *Channel {
+
+	c := Channel{
+		Title:         a.Title.String(),
+		Link:          atomAlternateLink(a.Link),
+		Description:   a.Subtitle.String(),
+		Language:      a.Language,
+		LastBuildDate: atomDate(a.Updated),
+		Subtitle:      a.ItunesSubtitle,
+		Owner:         a.ItunesOwner,
+	}
+
+	if c.Owner.Name == "" {
+		c.Owner = ItunesOwner{a.Author.Name, a.Author.Email}
+	}
+
+	c.Image = ImageAsset{URL: a.Logo, Title: c.Title, Link: c.Link}
+	if c.Image.URL == "" {
+		c.Image.URL = a.Icon
+	}
+
+	c.Item = make([]Item, len(a.Entry))
+	for i := range a.Entry {
+		c.Item[i] = atomEntryToItem(&a.Entry[i], &a.Author)
+	}
+
+	return &c
+}
+
+func atomEntryToItem(e *atomEntry, author *atomPerson) Item {
+
+	published := e.Published
+	if published == "" {
+		published = e.Updated
+	}
+
+	item := Item{
+		Title:    e.Title.String(),
+		Link:     atomAlternateLink(e.Link),
+		PubDate:  atomDate(published),
+		GUID:     e.Id,
+		Text:     e.Content.String(),
+		Duration: e.Duration,
+		Author:   e.Author.Name,
+	}
+
+	item.Description = e.Summary.String()
+	if item.Description == "" {
+		item.Description = item.Text
+	}
+
+	if item.Author == "" {
+		item.Author = author.Name
+	}
+
+	for _, c := range e.Category {
+		item.Category = append(item.Category, c.Term)
+	}
+
+	for _, l := range e.Link {
+		if l.Rel == "enclosure" {
+			item.Enclosure = append(item.Enclosure, ItemEnclosure{l.Href, l.Length, l.Type})
+		}
+	}
+
+	return item
+}
+
+// atomAlternateLink returns the rel="alternate" link, a link without rel means the same
+func atomAlternateLink(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// atomDate converts RFC 3339 timestamps into the RSS date format
+func atomDate(d string) RSSDate {
+	d = strings.TrimSpace(d)
+	t, err := time.Parse(time.RFC3339, d)
+	if err != nil {
+		return RSSDate(d)
+	}
+	return RSSDate(t.Format(WORDPRESS_DATE_FORMAT))
+}
@@ -0,0 +1,162 @@
+package feed
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnparseableDate = errors.New("unparseable date")
+
+	// layouts without the weekday, it is removed before parsing
+	dateLayouts = []string{
+		"2 Jan 2006 15:04:05 -0700",
+		"2 Jan 2006 15:04:05 -07:00",
+		"2 Jan 2006 15:04:05",
+		"2 Jan 2006 15:04 -0700",
+		"2 Jan 2006 15:04 -07:00",
+		"2 Jan 2006 15:04",
+		"2 January 2006 15:04:05 -0700",
+		"2 January 2006 15:04 -0700",
+		"2 January 2006 15:04:05",
+		"2 Jan 06 15:04:05 -0700",
+		"2 Jan 06 15:04 -0700",
+		"Jan 2 2006 15:04:05 -0700",
+		"Jan 2 2006 15:04 -0700",
+		"Jan 2 2006 15:04:05",
+		"January 2 2006 15:04:05 -0700",
+		"January 2 2006 15:04 -0700",
+		"Jan 2 15:04:05 -0700 2006", // ANSI C
+		"2 Jan 2006",
+		"2 January 2006",
+		"Jan 2 2006",
+		"January 2 2006",
+		"2006-01-02T15:04:05Z07:00",
+		"2006-01-02T15:04:05.999999999Z07:00",
+		"2006-01-02T15:04:05-0700",
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02 15:04:05 -0700",
+		"2006-01-02 15:04:05Z07:00",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+
+	// named zones are ambiguous, time.Parse would assume UTC for most of them
+	dateZones = map[string]string{
+		"UT":   "+0000",
+		"UTC":  "+0000",
+		"GMT":  "+0000",
+		"Z":    "+0000",
+		"WET":  "+0000",
+		"WEST": "+0100",
+		"BST":  "+0100",
+		"CET":  "+0100",
+		"MET":  "+0100",
+		"MEZ":  "+0100",
+		"CEST": "+0200",
+		"MEST": "+0200",
+		"MESZ": "+0200",
+		"EET":  "+0200",
+		"EEST": "+0300",
+		"MSK":  "+0300",
+		"IST":  "+0530",
+		"JST":  "+0900",
+		"AEST": "+1000",
+		"AEDT": "+1100",
+		"NZST": "+1200",
+		"NZDT": "+1300",
+		"AST":  "-0400",
+		"ADT":  "-0300",
+		"EST":  "-0500",
+		"EDT":  "-0400",
+		"CST":  "-0600",
+		"CDT":  "-0500",
+		"MST":  "-0700",
+		"MDT":  "-0600",
+		"PST":  "-0800",
+		"PDT":  "-0700",
+		"AKST": "-0900",
+		"AKDT": "-0800",
+		"HST":  "-1000",
+	}
+
+	// localized month names, Go only knows the english ones
+	dateMonths = map[string]string{
+		"januar":   "Jan",
+		"februar":  "Feb",
+		"mär":      "Mar",
+		"märz":     "Mar",
+		"mrz":      "Mar",
+		"mai":      "May",
+		"juni":     "Jun",
+		"juli":     "Jul",
+		"okt":      "Oct",
+		"oktober":  "Oct",
+		"dez":      "Dec",
+		"dezember": "Dec",
+		"sept":     "Sep",
+		"janv":     "Jan",
+		"févr":     "Feb",
+		"mars":     "Mar",
+		"avr":      "Apr",
+		"juin":     "Jun",
+		"juil":     "Jul",
+		"août":     "Aug",
+		"déc":      "Dec",
+	}
+
+	// a leading weekday in any language, e.g. "Mon," "Montag," "Di.," "lun.,"
+	dateWeekday = regexp.MustCompile(`^[^\d\s,]+\.?,\s*`)
+	// english weekday without a comma, e.g. "Mon 02 Jan 2006"
+	dateWeekdayEn = regexp.MustCompile(`(?i)^(mon|tue|wed|thu|fri|sat|sun)[a-z]*\.?\s+`)
+	dateSpaces    = regexp.MustCompile(`\s+`)
+)
+
+// parseDate tries hard to make sense of the dates found in real-world feeds
+func parseDate(s string) (time.Time, error) {
+
+	d := normalizeDate(s)
+	if d == "" {
+		return time.Time{}, ErrUnparseableDate
+	}
+
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, d)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, ErrUnparseableDate
+}
+
+func normalizeDate(s string) string {
+
+	d := strings.TrimSpace(dateSpaces.ReplaceAllString(s, " "))
+	d = dateWeekday.ReplaceAllString(d, "")
+	d = dateWeekdayEn.ReplaceAllString(d, "")
+
+	tokens := strings.Split(d, " ")
+	for i, t := range tokens {
+		// "01. Mar 2016" or "Mar. 01"
+		t = strings.TrimSuffix(t, ".")
+		t = strings.TrimSuffix(t, ",")
+
+		if m, ok := dateMonths[strings.ToLower(t)]; ok {
+			t = m
+		}
+		tokens[i] = t
+	}
+
+	// replace named zones with their offset
+	for i := 1; i < len(tokens); i++ {
+		if z, ok := dateZones[strings.ToUpper(tokens[i])]; ok {
+			tokens[i] = z
+		}
+	}
+
+	return strings.Join(tokens, " ")
+}
@@ -0,0 +1,143 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	MAX_DISCOVERED int = 3 // how many advertised feeds of a HTML page are tried
+)
+
+var (
+	ErrNotAFeed = errors.New("not a feed")
+)
+
+// Resolve fetches a url that is either a feed or a HTML page advertising feeds
+// with <link rel="alternate">. Response.Url is the url of the feed that was found.
+func Resolve(f Fetcher, u string) (*Channel, *Response, error) {
+	if f == nil {
+		f = DefaultFetcher
+	}
+
+	resp := Response{Url: u}
+	response, err := follow(context.Background(), f, &Request{Url: u}, &resp)
+	if err != nil {
+		return nil, &resp, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return nil, &resp, fmt.Errorf("feed: unexpected http status %d", response.StatusCode)
+	}
+
+	if !isFeedType(resp.ContentType) {
+		return nil, &resp, ErrNotAFeed
+	}
+
+	// the page or feed is kept in memory, it might have to be parsed twice
+	l := CurrentLimits()
+	body, err := ioutil.ReadAll(&limitedReader{response.Body, 0, l.MaxBytes})
+	resp.Bytes = (int64)(len(body))
+	if err != nil {
+		return nil, &resp, err
+	}
+
+	channel, total, err := parse(bytes.NewReader(body), l)
+	if err == nil {
+		resp.Items = total
+		resp.Truncated = l.MaxItems > 0 && total > l.MaxItems
+		return channel, &resp, nil
+	}
+
+	if !isHTML(resp.ContentType, body) {
+		return nil, &resp, ErrNotAFeed
+	}
+
+	base, _ := url.Parse(resp.Url)
+	for _, link := range Discover(bytes.NewReader(body), base) {
+		channel, r, err := Fetch(f, &Request{Url: link})
+		if err == nil && channel != nil {
+			return channel, r, nil
+		}
+	}
+
+	return nil, &resp, ErrNotAFeed
+}
+
+// Discover returns the feeds a HTML page advertises with <link rel="alternate">
+func Discover(r io.Reader, base *url.URL) []string {
+	links := make([]string, 0)
+	z := html.NewTokenizer(r)
+
+	for len(links) < MAX_DISCOVERED {
+		switch z.Next() {
+		case html.ErrorToken:
+			return links
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if string(name) == "body" {
+				return links // only the head is of interest
+			}
+			if string(name) != "link" || !hasAttr {
+				continue
+			}
+
+			var rel, typ, href string
+			for {
+				key, val, more := z.TagAttr()
+				switch string(key) {
+				case "rel":
+					rel = strings.ToLower(string(val))
+				case "type":
+					typ = strings.ToLower(string(val))
+				case "href":
+					href = strings.TrimSpace(string(val))
+				}
+				if !more {
+					break
+				}
+			}
+
+			if !strings.Contains(rel, "alternate") || href == "" {
+				continue
+			}
+			if !strings.Contains(typ, "rss+xml") && !strings.Contains(typ, "atom+xml") {
+				continue
+			}
+
+			link, err := url.Parse(href)
+			if err != nil {
+				continue
+			}
+			if base != nil {
+				link = base.ResolveReference(link)
+			}
+			links = append(links, link.String())
+		}
+	}
+
+	return links
+}
+
+func isHTML(contentType string, body []byte) bool {
+	if strings.Contains(strings.ToLower(contentType), "html") {
+		return true
+	}
+
+	if len(body) > 512 {
+		body = body[:512]
+	}
+
+	start := strings.ToLower(strings.TrimSpace(string(body)))
+	return strings.HasPrefix(start, "<!doctype html") || strings.HasPrefix(start, "<html")
+}
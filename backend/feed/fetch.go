@@ -0,0 +1,323 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mreiferson/go-httpclient"
+)
+
+const (
+	MAX_RETRIES                 = 2
+	MAX_REDIRECTS               = 5
+	RETRY_BACKOFF time.Duration = 2 // seconds, doubled on every retry
+)
+
+// DefaultFetcher is shared by all requests. It does not follow redirects,
+// Fetch does that itself in order to detect permanent ones.
+var DefaultFetcher Fetcher = &http.Client{
+	Transport: &httpclient.Transport{
+		ConnectTimeout:        DEFAULT_TIMEOUT * time.Second,
+		RequestTimeout:        RESPONSE_TIMEOUT * time.Second,
+		ResponseHeaderTimeout: DEFAULT_TIMEOUT * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Request for a feed, ETag and LastModified make it a conditional request
+type Request struct {
+	Url          string
+	ETag         string
+	LastModified string
+}
+
+// Response details of a feed request
+type Response struct {
+	Status       int
+	ETag         string
+	LastModified string
+	ContentType  string
+	Bytes        int64  // bytes read from the response body
+	Url          string // the url the feed was finally fetched from
+	Moved        string // target of a permanent redirect (301/308), empty otherwise
+	Attempts     int
+	Items        int  // items in the feed, including the ones skipped
+	Truncated    bool // more items than the limit, see SetLimits
+}
+
+// NotModified is true if the server answered a conditional request with 304
+func (r *Response) NotModified() bool {
+	return r.Status == http.StatusNotModified
+}
+
+// Fetch a feed and return its Channel struct, the response details and error.
+// The channel is nil if the feed was not modified since the last request.
+// Transient failures are retried, a nil fetcher means DefaultFetcher.
+// Feeds are read within the limits set with SetLimits, see Classify for the errors.
+func Fetch(f Fetcher, r *Request) (*Channel, *Response, error) {
+	return FetchContext(context.Background(), f, r)
+}
+
+// FetchContext is Fetch within the deadline of ctx, retries and waits for the host included
+func FetchContext(ctx context.Context, f Fetcher, r *Request) (*Channel, *Response, error) {
+	if f == nil {
+		f = DefaultFetcher
+	}
+
+	resp := Response{Url: r.Url}
+	response, err := follow(ctx, f, r, &resp)
+	if err != nil {
+		return nil, &resp, err
+	}
+	defer response.Body.Close()
+
+	if resp.NotModified() {
+		return nil, &resp, nil
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return nil, &resp, fmt.Errorf("feed: unexpected http status %d", response.StatusCode)
+	}
+	if !isFeedType(resp.ContentType) {
+		return nil, &resp, ErrContentType
+	}
+
+	l := CurrentLimits()
+	if l.MaxBytes > 0 && response.ContentLength > l.MaxBytes {
+		return nil, &resp, ErrFeedTooLarge
+	}
+
+	body := &limitedReader{response.Body, 0, l.MaxBytes}
+	channel, total, err := parse(body, l)
+	resp.Bytes = body.n
+	resp.Items = total
+	resp.Truncated = l.MaxItems > 0 && total > l.MaxItems
+
+	if l.MaxBytes > 0 && body.n > l.MaxBytes {
+		return nil, &resp, ErrFeedTooLarge
+	}
+	if err != nil && isHTML(resp.ContentType, nil) {
+		return nil, &resp, ErrNotAFeed
+	}
+
+	return channel, &resp, err
+}
+
+// follow sends the request and follows redirects, resp.Url is the final url and
+// resp.Moved the target of permanent redirects
+func follow(ctx context.Context, f Fetcher, r *Request, resp *Response) (*http.Response, error) {
+	permanent := true
+
+	for redirects := 0; ; redirects++ {
+		response, err := get(ctx, f, "GET", r, resp.Url, resp)
+		if err != nil {
+			return nil, err
+		}
+		if !isRedirect(response.StatusCode) {
+			resp.Status = response.StatusCode
+			resp.ETag = response.Header.Get("ETag")
+			resp.LastModified = response.Header.Get("Last-Modified")
+			resp.ContentType = response.Header.Get("Content-Type")
+
+			return response, nil
+		}
+
+		location, err := response.Location()
+		response.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if redirects == MAX_REDIRECTS {
+			return nil, fmt.Errorf("feed: too many redirects")
+		}
+
+		// only a chain of permanent redirects moves the feed
+		permanent = permanent && (response.StatusCode == http.StatusMovedPermanently || response.StatusCode == http.StatusPermanentRedirect)
+		if permanent {
+			resp.Moved = location.String()
+		}
+		resp.Url = location.String()
+	}
+}
+
+// Probe checks with a HEAD request if the server answers for the feed at all, without
+// downloading it. Redirects are not followed, a redirect counts as an answer.
+func Probe(f Fetcher, u string) (*Response, error) {
+	return ProbeContext(context.Background(), f, u)
+}
+
+// ProbeContext is Probe within the deadline of ctx
+func ProbeContext(ctx context.Context, f Fetcher, u string) (*Response, error) {
+	if f == nil {
+		f = DefaultFetcher
+	}
+
+	resp := Response{Url: u}
+	response, err := get(ctx, f, "HEAD", &Request{Url: u}, u, &resp)
+	if err != nil {
+		return &resp, err
+	}
+	response.Body.Close()
+
+	resp.Status = response.StatusCode
+	resp.ETag = response.Header.Get("ETag")
+	resp.LastModified = response.Header.Get("Last-Modified")
+
+	// some servers don't implement HEAD, that's still an answer
+	if response.StatusCode == http.StatusMethodNotAllowed || response.StatusCode == http.StatusNotImplemented {
+		return &resp, nil
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return &resp, fmt.Errorf("feed: unexpected http status %d", response.StatusCode)
+	}
+
+	return &resp, nil
+}
+
+// Download fetches any resource that is not a feed, e.g. artwork, the same way as feeds:
+// following redirects, retrying and politely. It fails with ErrFeedTooLarge after max bytes.
+func Download(f Fetcher, u string, max int64) ([]byte, *Response, error) {
+	if f == nil {
+		f = DefaultFetcher
+	}
+
+	resp := Response{Url: u}
+	response, err := follow(context.Background(), f, &Request{Url: u}, &resp)
+	if err != nil {
+		return nil, &resp, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return nil, &resp, fmt.Errorf("feed: unexpected http status %d", response.StatusCode)
+	}
+	if max > 0 && response.ContentLength > max {
+		return nil, &resp, ErrFeedTooLarge
+	}
+
+	body, err := ioutil.ReadAll(&limitedReader{response.Body, 0, max})
+	resp.Bytes = (int64)(len(body))
+	if err != nil {
+		return nil, &resp, err
+	}
+
+	return body, &resp, nil
+}
+
+// get sends a single request, retrying transient failures with a jittered backoff.
+// Every attempt waits for its turn at the host, see Configure. Nothing waits beyond the deadline of ctx.
+func get(ctx context.Context, f Fetcher, method string, r *Request, u string, resp *Response) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			err := sleep(ctx, backoff(attempt))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err := politeness.wait(ctx, f, u)
+		if err != nil {
+			return nil, err
+		}
+		resp.Attempts++
+
+		req, err := http.NewRequest(method, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("User-Agent", UserAgent())
+
+		// conditional request, if we know the validators from the last crawl
+		if r.ETag != "" {
+			req.Header.Set("If-None-Match", r.ETag)
+		}
+		if r.LastModified != "" {
+			req.Header.Set("If-Modified-Since", r.LastModified)
+		}
+
+		response, err := f.Do(req)
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt == MAX_RETRIES {
+			if err == nil && response.StatusCode == http.StatusTooManyRequests {
+				// still rate limited, try again with the next crawl
+				response.Body.Close()
+				return nil, ErrDeferred
+			}
+			return response, err
+		}
+
+		if err != nil {
+			if !isTransientError(err) {
+				return nil, err
+			}
+		} else if isTransientStatus(response.StatusCode) {
+			response.Body.Close()
+		} else {
+			return response, nil
+		}
+	}
+}
+
+// sleep waits for d, or returns early with the error of ctx
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func backoff(attempt int) time.Duration {
+	d := RETRY_BACKOFF * time.Second * time.Duration(1<<uint(attempt-1))
+	return d/2 + time.Duration(rand.Int63n((int64)(d)))
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
+
+func isTransientStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// isTransientError is true for network failures, but not for e.g. malformed urls
+func isTransientError(err error) bool {
+	if e, ok := err.(*url.Error); ok {
+		err = e.Err
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	if _, ok := err.(*net.OpError); ok {
+		return true
+	}
+	if e, ok := err.(net.Error); ok {
+		return e.Timeout() || e.Temporary()
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package feed
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	DEFAULT_MAX_BYTES        int64 = 20 * 1024 * 1024 // of a feed
+	DEFAULT_MAX_ITEMS        int   = 5000             // more items are skipped
+	DEFAULT_MAX_FIELD_LENGTH int   = 64 * 1024        // bytes, longer text fields are cut
+
+	// how a crawl ended, see Classify
+	CLASS_NETWORK      string = "network"
+	CLASS_HTTP         string = "http"
+	CLASS_DEFERRED     string = "deferred"
+	CLASS_TOO_LARGE    string = "too_large"
+	CLASS_CONTENT_TYPE string = "content_type"
+	CLASS_PARSE        string = "parse"
+	CLASS_TRUNCATED    string = "truncated" // no error, but only the first MaxItems items were read
+)
+
+var (
+	ErrFeedTooLarge = errors.New("feed: too large")
+	ErrContentType  = errors.New("feed: unexpected content type")
+
+	limits      = Limits{DEFAULT_MAX_BYTES, DEFAULT_MAX_ITEMS, DEFAULT_MAX_FIELD_LENGTH}
+	limitsMutex sync.Mutex
+
+	// media types that are never a feed, whatever the body looks like
+	nonFeedTypes = []string{"audio/", "video/", "image/", "font/", "application/pdf", "application/zip"}
+)
+
+// Limits protect the crawler against hostile or broken feeds, 0 means no limit
+type Limits struct {
+	MaxBytes       int64
+	MaxItems       int
+	MaxFieldLength int
+}
+
+// SetLimits replaces the default limits
+func SetLimits(l Limits) {
+	limitsMutex.Lock()
+	defer limitsMutex.Unlock()
+
+	limits = l
+}
+
+// CurrentLimits returns the limits in effect
+func CurrentLimits() Limits {
+	limitsMutex.Lock()
+	defer limitsMutex.Unlock()
+
+	return limits
+}
+
+// Classify tells apart why a crawl failed, or if it was truncated. It is empty for a complete crawl.
+func Classify(err error, resp *Response) string {
+	if err == nil {
+		if resp != nil && resp.Truncated {
+			return CLASS_TRUNCATED
+		}
+		return ""
+	}
+
+	switch err {
+	case ErrDeferred, ErrDisallowed:
+		return CLASS_DEFERRED
+	case ErrFeedTooLarge:
+		return CLASS_TOO_LARGE
+	case ErrContentType, ErrNotAFeed:
+		return CLASS_CONTENT_TYPE
+	case ErrUnsupportedFormat:
+		return CLASS_PARSE
+	}
+
+	switch err.(type) {
+	case *xml.SyntaxError, *xml.UnmarshalError:
+		return CLASS_PARSE
+	case *url.Error, net.Error:
+		return CLASS_NETWORK
+	}
+
+	if resp != nil && resp.Status >= 400 {
+		return CLASS_HTTP
+	}
+	if resp != nil && resp.Status != 0 {
+		// the server answered, something is wrong with the content, e.g. its charset
+		return CLASS_PARSE
+	}
+	return CLASS_NETWORK
+}
+
+// isFeedType is false for content types that are never a feed. Servers get the
+// types of feeds wrong in many ways, so everything else is given a chance.
+func isFeedType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+	for _, prefix := range nonFeedTypes {
+		if strings.HasPrefix(t, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// rssChannel is a Channel that streams its items, see itemCollector
+type rssChannel struct {
+	Channel
+	Items itemCollector `xml:"item"`
+}
+
+// itemCollector decodes one item at a time and skips all after max
+type itemCollector struct {
+	max   int
+	total int
+	items []Item
+}
+
+func (c *itemCollector) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	c.total++
+	if c.max > 0 && len(c.items) >= c.max {
+		return d.Skip()
+	}
+
+	var item Item
+	err := d.DecodeElement(&item, &start)
+	if err != nil {
+		return err
+	}
+	c.items = append(c.items, item)
+
+	return nil
+}
+
+// atomStream is an Atom feed that streams its entries
+type atomStream struct {
+	atomFeed
+	Entries entryCollector `xml:"entry"`
+}
+
+type entryCollector struct {
+	max     int
+	total   int
+	entries []atomEntry
+}
+
+func (c *entryCollector) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	c.total++
+	if c.max > 0 && len(c.entries) >= c.max {
+		return d.Skip()
+	}
+
+	var entry atomEntry
+	err := d.DecodeElement(&entry, &start)
+	if err != nil {
+		return err
+	}
+	c.entries = append(c.entries, entry)
+
+	return nil
+}
+
+// truncateChannel cuts the text fields of the channel and its items to max bytes
+func truncateChannel(c *Channel, max int) {
+	if max <= 0 {
+		return
+	}
+
+	for _, s := range []*string{&c.Title, &c.Link, &c.Description, &c.Subtitle, &c.NewFeedUrl} {
+		*s = truncate(*s, max)
+	}
+
+	for i := range c.Item {
+		item := &c.Item[i]
+		for _, s := range []*string{&item.Title, &item.Link, &item.Comments, &item.GUID, &item.Description, &item.Text, &item.Duration, &item.Author} {
+			*s = truncate(*s, max)
+		}
+	}
+}
+
+// truncate cuts s to at most max bytes without splitting a character
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
+
+// limitedReader counts the bytes read and fails with ErrFeedTooLarge after max bytes
+type limitedReader struct {
+	r   io.Reader
+	n   int64
+	max int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.max > 0 && l.n > l.max {
+		return 0, ErrFeedTooLarge
+	}
+
+	n, err := l.r.Read(p)
+	l.n += (int64)(n)
+	if l.max > 0 && l.n > l.max {
+		return n, ErrFeedTooLarge
+	}
+	return n, err
+}
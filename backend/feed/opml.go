@@ -0,0 +1,77 @@
+package feed
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rogpeppe/go-charset/charset"
+)
+
+//OPML document, see http://opml.org/spec2.opml
+type OPML struct {
+	XMLName xml.Name  `xml:"opml"`
+	Version string    `xml:"version,attr"`
+	Head    OPMLHead  `xml:"head"`
+	Body    []Outline `xml:"body>outline"`
+}
+
+type OPMLHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+//Outline is a feed, or a group of feeds if it has no XmlUrl
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XmlUrl   string    `xml:"xmlUrl,attr,omitempty"`
+	HtmlUrl  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+//ParseOPML returns the feed urls of all outlines, nested ones included
+func ParseOPML(r io.Reader) ([]string, error) {
+	xmlDecoder := xml.NewDecoder(r)
+	xmlDecoder.CharsetReader = charset.NewReader
+
+	var opml OPML
+	err := xmlDecoder.Decode(&opml)
+	if err != nil {
+		return nil, err
+	}
+
+	return outlineUrls(opml.Body), nil
+}
+
+func outlineUrls(oo []Outline) []string {
+	urls := make([]string, 0)
+	for _, o := range oo {
+		u := strings.TrimSpace(o.XmlUrl)
+		if u != "" {
+			urls = append(urls, u)
+		}
+		urls = append(urls, outlineUrls(o.Outlines)...)
+	}
+	return urls
+}
+
+//WriteOPML writes the outlines as an OPML 2.0 document
+func WriteOPML(w io.Writer, title string, outlines []Outline) error {
+	opml := OPML{
+		Version: "2.0",
+		Head:    OPMLHead{title, time.Now().UTC().Format(time.RFC1123Z)},
+		Body:    outlines,
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	return e.Encode(&opml)
+}
@@ -0,0 +1,65 @@
+package feed
+
+// Podcasting 2.0 namespace, see https://podcastindex.org/namespace/1.0
+
+type PodcastLocked struct {
+	Owner string `xml:"owner,attr"`
+	Value string `xml:",chardata"` // yes | no
+}
+
+type PodcastFunding struct {
+	Url  string `xml:"url,attr"`
+	Text string `xml:",chardata"`
+}
+
+type PodcastPerson struct {
+	Name  string `xml:",chardata"`
+	Role  string `xml:"role,attr"`
+	Group string `xml:"group,attr"`
+	Img   string `xml:"img,attr"`
+	Href  string `xml:"href,attr"`
+}
+
+type PodcastTranscript struct {
+	Url      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	Language string `xml:"language,attr"`
+	Rel      string `xml:"rel,attr"`
+}
+
+//PodcastChapters links to an external chapters file
+type PodcastChapters struct {
+	Url  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type PodcastSoundbite struct {
+	StartTime string `xml:"startTime,attr"`
+	Duration  string `xml:"duration,attr"`
+	Title     string `xml:",chardata"`
+}
+
+type PodcastValue struct {
+	Type       string                  `xml:"type,attr"`
+	Method     string                  `xml:"method,attr"`
+	Suggested  string                  `xml:"suggested,attr"`
+	Recipients []PodcastValueRecipient `xml:"https://podcastindex.org/namespace/1.0 valueRecipient"`
+}
+
+type PodcastValueRecipient struct {
+	Name        string `xml:"name,attr"`
+	Type        string `xml:"type,attr"`
+	Address     string `xml:"address,attr"`
+	Split       string `xml:"split,attr"`
+	Fee         string `xml:"fee,attr"`
+	CustomKey   string `xml:"customKey,attr"`
+	CustomValue string `xml:"customValue,attr"`
+}
+
+//PscChapter is an inline chapter mark, see http://podlove.org/simple-chapters
+type PscChapter struct {
+	Start string `xml:"start,attr"`
+	Title string `xml:"title,attr"`
+	Href  string `xml:"href,attr"`
+	Image string `xml:"image,attr"`
+}
@@ -0,0 +1,125 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DEFAULT_USER_AGENT string        = "mindcastio/1.0 (+https://mindcast.io/crawler)"
+	MAX_HOST_WAIT      time.Duration = 10 * time.Second // longer waits for a host are deferred
+	MAX_HOSTS          int           = 10000            // hosts remembered before stale ones are dropped
+)
+
+var (
+	// ErrDeferred means the request was not sent to spare the host, it should be tried again later
+	ErrDeferred = errors.New("feed: deferred, host is busy")
+	// ErrDisallowed means robots.txt does not allow us to fetch the url
+	ErrDisallowed = errors.New("feed: disallowed by robots.txt")
+
+	politeness = polite{
+		userAgent: DEFAULT_USER_AGENT,
+		next:      make(map[string]time.Time),
+		robots:    make(map[string]*robotsEntry),
+	}
+)
+
+type polite struct {
+	sync.Mutex
+
+	userAgent   string
+	hostSpacing time.Duration
+	useRobots   bool
+
+	next   map[string]time.Time // earliest time for the next request per host
+	robots map[string]*robotsEntry
+}
+
+// Configure sets the crawler identity and how polite it is to hosts: at least spacing
+// between two requests to the same host, and if robots.txt is honored
+func Configure(userAgent string, spacing time.Duration, robots bool) {
+	politeness.Lock()
+	defer politeness.Unlock()
+
+	if userAgent != "" {
+		politeness.userAgent = userAgent
+	}
+	politeness.hostSpacing = spacing
+	politeness.useRobots = robots
+}
+
+// UserAgent is sent with every request
+func UserAgent() string {
+	politeness.Lock()
+	defer politeness.Unlock()
+
+	return politeness.userAgent
+}
+
+// IsDeferred is true for errors that are no fault of the feed, the crawl was skipped for politeness
+func IsDeferred(err error) bool {
+	return err == ErrDeferred || err == ErrDisallowed
+}
+
+// wait checks robots.txt and waits for a slot to send a request to the host of u.
+// It returns ErrDeferred instead of waiting longer than MAX_HOST_WAIT.
+func (p *polite) wait(ctx context.Context, f Fetcher, u string) error {
+	target, err := url.Parse(u)
+	if err != nil || target.Host == "" {
+		return nil // the request itself fails with a proper error
+	}
+	host := strings.ToLower(target.Host)
+
+	p.Lock()
+	useRobots := p.useRobots
+	p.Unlock()
+
+	if useRobots && !strings.HasSuffix(target.Path, "/robots.txt") {
+		if !p.allowed(ctx, f, target) {
+			return ErrDisallowed
+		}
+	}
+
+	d, err := p.reserve(host, time.Now())
+	if err != nil {
+		return err
+	}
+	if d > 0 {
+		return sleep(ctx, d)
+	}
+	return nil
+}
+
+// reserve books the next slot for a host and returns how long to wait for it
+func (p *polite) reserve(host string, now time.Time) (time.Duration, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.hostSpacing <= 0 {
+		return 0, nil
+	}
+
+	next := p.next[host]
+	if next.Before(now) {
+		next = now
+	}
+	d := next.Sub(now)
+	if d > MAX_HOST_WAIT {
+		return 0, ErrDeferred
+	}
+
+	if len(p.next) >= MAX_HOSTS {
+		for h, t := range p.next {
+			if t.Before(now) {
+				delete(p.next, h)
+			}
+		}
+	}
+	p.next[host] = next.Add(p.hostSpacing)
+
+	return d, nil
+}
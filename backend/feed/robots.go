@@ -0,0 +1,194 @@
+package feed
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	ROBOTS_TTL       time.Duration = 24 * time.Hour
+	ROBOTS_ERROR_TTL time.Duration = time.Hour  // a host without a working robots.txt is asked again sooner
+	MAX_ROBOTS       int64         = 512 * 1024 // bytes, the rest of a robots.txt is ignored
+)
+
+type (
+	robotsEntry struct {
+		rules   []robotsRule
+		expires time.Time
+	}
+
+	robotsRule struct {
+		allow bool
+		path  string
+	}
+)
+
+// allowed checks the url against the cached robots.txt of its host, which is fetched if needed
+func (p *polite) allowed(ctx context.Context, f Fetcher, u *url.URL) bool {
+	key := u.Scheme + "://" + strings.ToLower(u.Host)
+	now := time.Now()
+
+	p.Lock()
+	e, found := p.robots[key]
+	agent := p.userAgent
+	p.Unlock()
+
+	if !found || now.After(e.expires) {
+		e = fetchRobots(ctx, f, key, agent)
+
+		p.Lock()
+		if len(p.robots) >= MAX_HOSTS {
+			for k, r := range p.robots {
+				if now.After(r.expires) {
+					delete(p.robots, k)
+				}
+			}
+		}
+		p.robots[key] = e
+		p.Unlock()
+	}
+
+	return e.allows(u.RequestURI())
+}
+
+// fetchRobots gets the rules for us from a host. Without a robots.txt everything is allowed,
+// and also if it can't be fetched right now, to not stop crawling because of an outage.
+func fetchRobots(ctx context.Context, f Fetcher, base string, agent string) *robotsEntry {
+	u := base + "/robots.txt"
+
+	resp := Response{Url: u}
+	response, err := follow(ctx, f, &Request{Url: u}, &resp)
+	if err != nil {
+		return &robotsEntry{nil, time.Now().Add(ROBOTS_ERROR_TTL)}
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		return &robotsEntry{nil, time.Now().Add(ROBOTS_ERROR_TTL)}
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return &robotsEntry{nil, time.Now().Add(ROBOTS_TTL)}
+	}
+
+	return &robotsEntry{parseRobots(io.LimitReader(response.Body, MAX_ROBOTS), agent), time.Now().Add(ROBOTS_TTL)}
+}
+
+// parseRobots returns the rules of the group for our user agent, or of the * group if there is none
+func parseRobots(r io.Reader, userAgent string) []robotsRule {
+	token := robotsToken(userAgent)
+
+	var ours, star []robotsRule
+	foundOurs := false
+	matchOurs, matchAny := false, false
+	inRules := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				// a new group starts
+				matchOurs, matchAny = false, false
+				inRules = false
+			}
+			agent := robotsToken(value)
+			if agent == "*" {
+				matchAny = true
+			} else if agent != "" && agent == token {
+				matchOurs = true
+				foundOurs = true
+			}
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue // "Disallow:" allows everything
+			}
+			rule := robotsRule{key == "allow", value}
+			if matchOurs {
+				ours = append(ours, rule)
+			}
+			if matchAny {
+				star = append(star, rule)
+			}
+		}
+	}
+
+	if foundOurs {
+		return ours
+	}
+	return star
+}
+
+// robotsToken is the product name of a user agent, e.g. "mindcastio" of "mindcastio/1.0 (+https://...)"
+func robotsToken(userAgent string) string {
+	token := strings.ToLower(strings.TrimSpace(userAgent))
+	if i := strings.IndexAny(token, "/ "); i > 0 {
+		token = token[:i]
+	}
+	return token
+}
+
+// allows applies the most specific (longest) matching rule, allow wins a tie
+func (e *robotsEntry) allows(path string) bool {
+	allow := true
+	longest := -1
+
+	for _, rule := range e.rules {
+		if !robotsMatch(rule.path, path) {
+			continue
+		}
+		if len(rule.path) > longest || (len(rule.path) == longest && rule.allow) {
+			allow = rule.allow
+			longest = len(rule.path)
+		}
+	}
+
+	return allow
+}
+
+// robotsMatch matches a path against a robots.txt pattern, * is any sequence of characters
+// and a trailing $ anchors the pattern at the end of the path
+func robotsMatch(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+
+	pos := len(parts[0])
+	for _, part := range parts[1:] {
+		i := strings.Index(path[pos:], part)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(part)
+	}
+
+	if anchored {
+		if len(parts) == 1 {
+			return path == parts[0]
+		}
+		return strings.HasSuffix(path, parts[len(parts)-1])
+	}
+	return true
+}
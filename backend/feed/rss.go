@@ -2,10 +2,11 @@ package feed
 
 import (
 	"encoding/xml"
+	"errors"
+	"io"
 	"net/http"
 	"time"
 
-	"github.com/mreiferson/go-httpclient"
 	"github.com/rogpeppe/go-charset/charset"
 	_ "github.com/rogpeppe/go-charset/data" //initialize only
 )
@@ -16,9 +17,13 @@ const (
 	RESPONSE_TIMEOUT      time.Duration = 120 // seconds
 )
 
-//Fetcher interface
+var (
+	ErrUnsupportedFormat = errors.New("unsupported feed format")
+)
+
+//Fetcher sends the http requests, *http.Client implements it
 type Fetcher interface {
-	Get(url string) (resp *http.Response, err error)
+	Do(req *http.Request) (resp *http.Response, err error)
 }
 
 //Date type
@@ -26,30 +31,54 @@ type RSSDate string
 
 //Channel struct for RSS
 type Channel struct {
-	Title         string      `xml:"title"`
-	Link          string      `xml:"link"`
-	Description   string      `xml:"description"`
-	Language      string      `xml:"language"`
-	LastBuildDate RSSDate     `xml:"lastBuildDate"`
-	Image         ImageAsset  `xml:"image"`
-	Subtitle      string      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd subtitle"`
-	Owner         ItunesOwner `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd owner"`
-	Item          []Item      `xml:"item"`
+	Title         string           `xml:"title"`
+	Link          string           `xml:"link"`
+	Description   string           `xml:"description"`
+	Language      string           `xml:"language"`
+	LastBuildDate RSSDate          `xml:"lastBuildDate"`
+	ItunesImage   ItunesImage      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"` // before Image, otherwise Image takes it
+	Image         ImageAsset       `xml:"image"`
+	Subtitle      string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd subtitle"`
+	Owner         ItunesOwner      `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd owner"`
+	Category      []ItunesCategory `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd category"`
+	Explicit      string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+	Type          string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd type"`
+	Block         string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd block"`
+	Complete      string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd complete"`
+	NewFeedUrl    string           `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd new-feed-url"`
+	PodcastGuid   string           `xml:"https://podcastindex.org/namespace/1.0 guid"`
+	Locked        PodcastLocked    `xml:"https://podcastindex.org/namespace/1.0 locked"`
+	Funding       []PodcastFunding `xml:"https://podcastindex.org/namespace/1.0 funding"`
+	Person        []PodcastPerson  `xml:"https://podcastindex.org/namespace/1.0 person"`
+	Value         *PodcastValue    `xml:"https://podcastindex.org/namespace/1.0 value"`
+	Item          []Item           `xml:"item"`
 }
 
 //Item struct for each Item in the Channel
 type Item struct {
-	Title       string          `xml:"title"`
-	Link        string          `xml:"link"`
-	Comments    string          `xml:"comments"`
-	PubDate     RSSDate         `xml:"pubDate"`
-	GUID        string          `xml:"guid"`
-	Category    []string        `xml:"category"`
-	Enclosure   []ItemEnclosure `xml:"enclosure"`
-	Description string          `xml:"description"`
-	Text        string          `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
-	Duration    string          `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
-	Author      string          `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+	Title       string              `xml:"title"`
+	Link        string              `xml:"link"`
+	Comments    string              `xml:"comments"`
+	PubDate     RSSDate             `xml:"pubDate"`
+	GUID        string              `xml:"guid"`
+	Category    []string            `xml:"category"`
+	Enclosure   []ItemEnclosure     `xml:"enclosure"`
+	Description string              `xml:"description"`
+	Text        string              `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Duration    string              `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd duration"`
+	Author      string              `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd author"`
+	Image       ItunesImage         `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+	Explicit    string              `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd explicit"`
+	Episode     string              `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episode"`
+	Season      string              `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd season"`
+	EpisodeType string              `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd episodeType"`
+	Block       string              `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd block"`
+	Transcript  []PodcastTranscript `xml:"https://podcastindex.org/namespace/1.0 transcript"`
+	Chapters    PodcastChapters     `xml:"https://podcastindex.org/namespace/1.0 chapters"`
+	Person      []PodcastPerson     `xml:"https://podcastindex.org/namespace/1.0 person"`
+	Soundbite   []PodcastSoundbite  `xml:"https://podcastindex.org/namespace/1.0 soundbite"`
+	Value       *PodcastValue       `xml:"https://podcastindex.org/namespace/1.0 value"`
+	PscChapters []PscChapter        `xml:"http://podlove.org/simple-chapters chapters>chapter"`
 }
 
 //ItemEnclosure struct for each Item Enclosure
@@ -71,14 +100,23 @@ type ItunesOwner struct {
 	Email string `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd email"`
 }
 
-//Parse (Date function) and returns Time, error
+type ItunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+//ItunesCategory can have sub-categories
+type ItunesCategory struct {
+	Text     string           `xml:"text,attr"`
+	Category []ItunesCategory `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd category"`
+}
+
+//Parse (Date function) and returns Time, error. The error is ErrUnparseableDate if none of the known formats matches.
 func (d RSSDate) Parse() (time.Time, error) {
 	t, err := d.ParseWithFormat(WORDPRESS_DATE_FORMAT)
 	if err != nil {
-		t, err = d.ParseWithFormat(time.RFC1123) // variation of the wordpress format
-		if err != nil {
-			t, err = d.ParseWithFormat(time.RFC822) // RSS 2.0 spec
-		}
+		// RFC1123, RFC822 and anything else found in the wild. time.RFC1123 is
+		// not used directly as it treats named zones other than UTC as UTC.
+		t, err = parseDate(string(d))
 	}
 	return t, err
 }
@@ -106,35 +144,69 @@ func (d RSSDate) MustFormat(format string) string {
 	return s
 }
 
-//Read a string url and returns a Channel struct, error
-func RSS(url string) (*Channel, error) {
-	//return ReadWithClient(url, http.DefaultClient)
-	transport := &httpclient.Transport{
-		ConnectTimeout:        DEFAULT_TIMEOUT * time.Second,
-		RequestTimeout:        RESPONSE_TIMEOUT * time.Second,
-		ResponseHeaderTimeout: DEFAULT_TIMEOUT * time.Second,
-	}
-	defer transport.Close()
+//Read a string url and returns a Channel struct, error. A nil Fetcher means DefaultFetcher
+func RSS(url string, f Fetcher) (*Channel, error) {
+	channel, _, err := Fetch(f, &Request{Url: url})
+	return channel, err
+}
 
-	client := &http.Client{Transport: transport}
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	response, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer response.Body.Close()
+//Parse detects the feed format (RSS 2.0 or Atom 1.0) and returns a Channel struct, error.
+//It stays within the limits set with SetLimits.
+func Parse(r io.Reader) (*Channel, error) {
+	channel, _, err := parse(r, CurrentLimits())
+	return channel, err
+}
 
-	xmlDecoder := xml.NewDecoder(response.Body)
+// parse streams the items of the feed, only the first l.MaxItems are kept. It also
+// returns the total number of items.
+func parse(r io.Reader, l Limits) (*Channel, int, error) {
+	xmlDecoder := xml.NewDecoder(r)
 	xmlDecoder.CharsetReader = charset.NewReader
 
-	var rss struct {
-		Channel Channel `xml:"channel"`
-	}
-	if err = xmlDecoder.Decode(&rss); err != nil {
-		return nil, err
+	// find the root element
+	for {
+		token, err := xmlDecoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil, 0, ErrUnsupportedFormat
+			}
+			return nil, 0, err
+		}
+
+		root, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch root.Name.Local {
+		case "rss":
+			var rss struct {
+				Channel rssChannel `xml:"channel"`
+			}
+			rss.Channel.Items.max = l.MaxItems
+			if err = xmlDecoder.DecodeElement(&rss, &root); err != nil {
+				return nil, 0, err
+			}
+
+			channel := rss.Channel.Channel
+			channel.Item = rss.Channel.Items.items
+			truncateChannel(&channel, l.MaxFieldLength)
+
+			return &channel, rss.Channel.Items.total, nil
+		case "feed":
+			var atom atomStream
+			atom.Entries.max = l.MaxItems
+			if err = xmlDecoder.DecodeElement(&atom, &root); err != nil {
+				return nil, 0, err
+			}
+			atom.Entry = atom.Entries.entries
+
+			channel := atomToChannel(&atom.atomFeed)
+			truncateChannel(channel, l.MaxFieldLength)
+
+			return channel, atom.Entries.total, nil
+		default:
+			return nil, 0, ErrUnsupportedFormat
+		}
 	}
-	return &rss.Channel, nil
 }
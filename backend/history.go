@@ -0,0 +1,53 @@
+package backend
+
+import (
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/backend/datastore"
+)
+
+// CrawlHistoryAdd logs a crawl attempt, only the last CRAWL_HISTORY entries per podcast are kept
+func CrawlHistoryAdd(h *CrawlHistory) error {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	crawl_history := ds.Collection(datastore.CRAWL_HISTORY_COL)
+
+	err := crawl_history.Insert(h)
+	if err != nil {
+		return err
+	}
+
+	// remove everything older than the last CRAWL_HISTORY entries
+	oldest := CrawlHistory{}
+	err = crawl_history.Find(bson.M{"uid": h.Uid}).Sort("-created").Skip(CRAWL_HISTORY).One(&oldest)
+	if err == nil {
+		_, err = crawl_history.RemoveAll(bson.M{"uid": h.Uid, "created": bson.M{"$lte": oldest.Created}})
+		return err
+	}
+
+	return nil
+}
+
+// CrawlHistoryLookup returns a page of the crawl history of a podcast, latest first
+func CrawlHistoryLookup(uid string, page int, size int) (*CrawlHistoryPage, error) {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	crawl_history := ds.Collection(datastore.CRAWL_HISTORY_COL)
+
+	total, err := crawl_history.Find(bson.M{"uid": uid}).Count()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []CrawlHistory{}
+	err = crawl_history.Find(bson.M{"uid": uid}).Sort("-created").Skip((page - 1) * size).Limit(size).All(&entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CrawlHistoryPage{uid, page, size, total, entries}, nil
+}
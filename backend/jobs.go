@@ -0,0 +1,154 @@
+package backend
+
+import (
+	"strings"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/backend/datastore"
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+// SubmitJobAdd queues a feed submission, the crawler picks it up
+func SubmitJobAdd(feed string) (*SubmitJob, error) {
+
+	id, err := util.UUID()
+	if err != nil {
+		return nil, err
+	}
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	submit_jobs := ds.Collection(datastore.SUBMIT_JOBS_COL)
+
+	now := util.Timestamp()
+	job := SubmitJob{id, strings.TrimSpace(feed), JOB_QUEUED, "", "", now, now}
+
+	err = submit_jobs.Insert(&job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func SubmitJobLookup(id string) *SubmitJob {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	submit_jobs := ds.Collection(datastore.SUBMIT_JOBS_COL)
+
+	job := SubmitJob{}
+	submit_jobs.Find(bson.M{"id": id}).One(&job)
+
+	if job.Id == "" {
+		return nil
+	} else {
+		return &job
+	}
+}
+
+// SubmitJobNext claims the oldest queued job, or one that got stuck while crawling
+func SubmitJobNext() *SubmitJob {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	submit_jobs := ds.Collection(datastore.SUBMIT_JOBS_COL)
+
+	now := util.Timestamp()
+	q := bson.M{"$or": []bson.M{
+		{"status": JOB_QUEUED},
+		{"status": JOB_CRAWLING, "updated": bson.M{"$lt": now - SUBMIT_JOB_TIMEOUT}},
+	}}
+	change := mgo.Change{
+		Update:    bson.M{"$set": bson.M{"status": JOB_CRAWLING, "updated": now}},
+		ReturnNew: true,
+	}
+
+	job := SubmitJob{}
+	_, err := submit_jobs.Find(q).Sort("created").Apply(change, &job)
+	if err != nil {
+		return nil
+	}
+
+	return &job
+}
+
+// SubmitJobClaim claims a specific job if it is still queued, it may already have been
+// picked up by another crawler
+func SubmitJobClaim(id string) *SubmitJob {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	submit_jobs := ds.Collection(datastore.SUBMIT_JOBS_COL)
+
+	change := mgo.Change{
+		Update:    bson.M{"$set": bson.M{"status": JOB_CRAWLING, "updated": util.Timestamp()}},
+		ReturnNew: true,
+	}
+
+	job := SubmitJob{}
+	_, err := submit_jobs.Find(bson.M{"id": id, "status": JOB_QUEUED}).Apply(change, &job)
+	if err != nil {
+		return nil
+	}
+
+	return &job
+}
+
+func SubmitJobUpdate(id string, status string, reason string, uid string) error {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	submit_jobs := ds.Collection(datastore.SUBMIT_JOBS_COL)
+
+	return submit_jobs.Update(bson.M{"id": id}, bson.M{"$set": bson.M{"status": status, "reason": reason, "uid": uid, "updated": util.Timestamp()}})
+}
+
+// SubmitJobState is the state a job for a known podcast has reached: queued as long as the podcast
+// was never crawled, indexed if it, or the one it was merged into, is searchable already, crawled otherwise
+func SubmitJobState(uid string) string {
+	p := PodcastLookup(uid)
+	if p != nil && p.MergedInto != "" {
+		p = PodcastLookup(p.MergedInto)
+	}
+	if p == nil {
+		return JOB_QUEUED
+	}
+	if p.Version >= SEARCH_REVISION {
+		return JOB_INDEXED
+	}
+	return JOB_CRAWLED
+}
+
+// SubmitJobIndexed moves the crawled jobs of a podcast on, it is searchable now
+func SubmitJobIndexed(uid string) error {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	submit_jobs := ds.Collection(datastore.SUBMIT_JOBS_COL)
+
+	_, err := submit_jobs.UpdateAll(bson.M{"uid": uid, "status": JOB_CRAWLED}, bson.M{"$set": bson.M{"status": JOB_INDEXED, "updated": util.Timestamp()}})
+	return err
+}
+
+// SubmitJobCrawledUids returns the podcasts with jobs waiting for them to be indexed
+func SubmitJobCrawledUids() []string {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	submit_jobs := ds.Collection(datastore.SUBMIT_JOBS_COL)
+
+	uids := []string{}
+	submit_jobs.Find(bson.M{"status": JOB_CRAWLED}).Distinct("uid", &uids)
+
+	return uids
+}
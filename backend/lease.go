@@ -0,0 +1,61 @@
+package backend
+
+import (
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/backend/datastore"
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+// IndexClaim leases up to limit podcasts matching the query to a crawler instance, each
+// one with an atomic find-and-modify so that no two instances get the same podcast.
+// Podcasts with a lease that expired are claimed again, the crawler holding it is gone.
+func IndexClaim(owner string, q bson.M, limit int) []PodcastIndex {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	results := []PodcastIndex{}
+	for len(results) < limit {
+		now := util.Timestamp()
+
+		// no lease yet, or an expired one
+		claim := bson.M{"leaseexpires": bson.M{"$not": bson.M{"$gte": now}}}
+		for k, v := range q {
+			claim[k] = v
+		}
+
+		change := mgo.Change{
+			Update:    bson.M{"$set": bson.M{"leaseowner": owner, "leaseexpires": now + CRAWL_LEASE}},
+			ReturnNew: true,
+		}
+
+		i := PodcastIndex{}
+		_, err := main_index.Find(claim).Sort("next").Apply(change, &i)
+		if err != nil {
+			break // mgo.ErrNotFound, nothing left to claim
+		}
+		results = append(results, i)
+	}
+
+	return results
+}
+
+// IndexRelease gives up a lease returned by IndexClaim. A lease that expired and was claimed
+// again, by another instance or this one, is left alone.
+func IndexRelease(idx *PodcastIndex) error {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	err := main_index.Update(bson.M{"uid": idx.Uid, "leaseowner": idx.LeaseOwner, "leaseexpires": idx.LeaseExpires}, bson.M{"$set": bson.M{"leaseowner": "", "leaseexpires": 0}})
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
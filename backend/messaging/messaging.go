@@ -1,18 +1,45 @@
 package messaging
 
 import (
+	"encoding/json"
+	"errors"
+
 	"github.com/nats-io/nats"
 
 	"github.com/mindcastio/mindcastio/backend/environment"
 	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/util"
 )
 
-type MessageBroker struct {
-	connection *nats.Conn
-}
+const (
+	SUBMIT_SUBJECT  string = "mindcastio.submit"  // a feed was submitted, Id is the submit job
+	CRAWLED_SUBJECT string = "mindcastio.crawled" // a crawl attempt completed, Id is the podcast
+	INDEX_SUBJECT   string = "mindcastio.index"   // a podcast or its episodes changed, Id is the podcast
+
+	CRAWLER_QUEUE string = "crawler"
+	INDEXER_QUEUE string = "indexer"
+)
+
+type (
+	MessageBroker struct {
+		connection *nats.Conn
+	}
+
+	Event struct {
+		Id      string `json:"id"`
+		Status  string `json:"status"`
+		Created int64  `json:"created"`
+	}
+)
+
+var (
+	ErrNotConnected = errors.New("messaging not connected")
+)
 
 var broker *MessageBroker
 
+// Initialize connects to NATS. Messaging is optional, without it the services
+// fall back to their periodic sweeps.
 func Initialize(env *environment.Environment) {
 
 	logger.Log("messaging.initialize", env.MessagingServiceUrls()[0])
@@ -24,7 +51,8 @@ func Initialize(env *environment.Environment) {
 
 	nc, err := opts.Connect()
 	if err != nil {
-		panic(err)
+		logger.Error("messaging.initialize.error", err, env.MessagingServiceUrls()...)
+		return
 	}
 	br.connection = nc
 
@@ -35,17 +63,58 @@ func Initialize(env *environment.Environment) {
 func Shutdown() {
 	logger.Log("messaging.shutdown")
 
-	broker.connection.Close()
+	if broker != nil {
+		broker.connection.Close()
+	}
+}
+
+func Enabled() bool {
+	return broker != nil
 }
 
 func Send(subj string, msg string) error {
+	if broker == nil {
+		return ErrNotConnected
+	}
 	return broker.connection.Publish(subj, []byte(msg))
 }
 
-func Subscribe(subj string, callback func(msg *nats.Msg)) {
-	broker.connection.Subscribe(subj, callback)
+// Publish sends an event, it is dropped silently if messaging is not available
+func Publish(subj string, id string, status string) {
+	if broker == nil {
+		return
+	}
+
+	msg, err := json.Marshal(&Event{id, status, util.Timestamp()})
+	if err == nil {
+		err = broker.connection.Publish(subj, msg)
+	}
+	if err != nil {
+		logger.Warn("messaging.publish.error", subj, id, err.Error())
+	}
+}
+
+// Decode returns the event of a message sent with Publish
+func Decode(msg *nats.Msg) (*Event, error) {
+	e := Event{}
+	err := json.Unmarshal(msg.Data, &e)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
 }
 
-func QueueSubscribe(subj string, queue string, callback func(msg *nats.Msg)) {
-	broker.connection.QueueSubscribe(subj, queue, callback)
+// Subscribe returns the subscription, unsubscribe on shutdown before waiting for the callbacks
+func Subscribe(subj string, callback func(msg *nats.Msg)) (*nats.Subscription, error) {
+	if broker == nil {
+		return nil, ErrNotConnected
+	}
+	return broker.connection.Subscribe(subj, callback)
+}
+
+func QueueSubscribe(subj string, queue string, callback func(msg *nats.Msg)) (*nats.Subscription, error) {
+	if broker == nil {
+		return nil, ErrNotConnected
+	}
+	return broker.connection.QueueSubscribe(subj, queue, callback)
 }
@@ -42,12 +42,16 @@ func StatusResponse(w rest.ResponseWriter, status int) {
 }
 
 func JsonApiResponse(w rest.ResponseWriter, model interface{}) error {
+	return JsonApiStatusResponse(w, http.StatusOK, model)
+}
+
+func JsonApiStatusResponse(w rest.ResponseWriter, status int, model interface{}) error {
 	payload, err := jsonapi.MarshalOne(model)
 	if err != nil {
 		return err
 	}
 
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 	err = w.WriteJson(payload)
 
 	return err
@@ -66,6 +70,10 @@ func JsonApiArrayResponse(w rest.ResponseWriter, models []interface{}) error {
 }
 
 func JsonApiErrorResponse(w rest.ResponseWriter, code string, message string, err error) {
+	JsonApiErrorStatusResponse(w, http.StatusBadRequest, code, message, err)
+}
+
+func JsonApiErrorStatusResponse(w rest.ResponseWriter, status int, code string, message string, err error) {
 	var msg string = message
 	if err != nil {
 		msg = err.Error()
@@ -75,13 +83,13 @@ func JsonApiErrorResponse(w rest.ResponseWriter, code string, message string, er
 	errors := make([]Error, 1)
 	errors[0] = Error{
 		uuid,
-		strconv.Itoa(http.StatusBadRequest),
+		strconv.Itoa(status),
 		code,
 		message,
 		msg,
 	}
 
-	w.WriteHeader(http.StatusBadRequest)
+	w.WriteHeader(status)
 	ee := w.WriteJson(JsonApiError{&errors})
 	if ee != nil {
 		panic(ee)
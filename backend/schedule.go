@@ -0,0 +1,75 @@
+package backend
+
+import (
+	"sort"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/backend/datastore"
+	"github.com/mindcastio/mindcastio/backend/environment"
+)
+
+// updateRate estimates how often a podcast should be crawled, based on the
+// intervals between its most recent episodes. A podcast publishing daily is
+// checked hourly, a dormant podcast only once in a while.
+func updateRate(uid string, now int64) *RateChange {
+
+	env := environment.GetEnvironment()
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	episodes_metadata := ds.Collection(datastore.EPISODES_COL)
+
+	episodes := []EpisodeMetadata{}
+	episodes_metadata.Find(bson.M{"podcastuid": uid}).Sort("-published").Limit(RATE_SAMPLES).Select(bson.M{"published": 1}).All(&episodes)
+
+	change := RateChange{DEFAULT_UPDATE_RATE, 0, len(episodes), false, now}
+
+	if len(episodes) < 2 {
+		change.Rate = boundRate(DEFAULT_UPDATE_RATE, env.CrawlerMinRate(), env.CrawlerMaxRate())
+		return &change
+	}
+
+	// intervals between consecutive episodes in minutes
+	intervals := make([]int, 0, len(episodes)-1)
+	for i := 1; i < len(episodes); i++ {
+		d := (int)((episodes[i-1].Published - episodes[i].Published) / 60)
+		if d > 0 {
+			intervals = append(intervals, d)
+		}
+	}
+
+	if len(intervals) == 0 {
+		change.Rate = boundRate(DEFAULT_UPDATE_RATE, env.CrawlerMinRate(), env.CrawlerMaxRate())
+		return &change
+	}
+
+	sort.Ints(intervals)
+	change.Interval = intervals[len(intervals)/2] // median
+
+	// a podcast that is overdue is checked less often
+	since := (int)((now - episodes[0].Published) / 60)
+	if since > change.Interval {
+		change.Interval = since
+	}
+
+	if since > DORMANT_PERIOD {
+		change.Dormant = true
+		change.Rate = env.CrawlerMaxRate()
+	} else {
+		change.Rate = boundRate(change.Interval/RATE_CHECKS_PER_INTERVAL, env.CrawlerMinRate(), env.CrawlerMaxRate())
+	}
+
+	return &change
+}
+
+func boundRate(rate int, min int, max int) int {
+	if rate < min {
+		return min
+	}
+	if rate > max {
+		return max
+	}
+	return rate
+}
@@ -5,10 +5,44 @@ const (
 	DEFAULT_UPDATE_RATE        int   = 1440 // min.
 	DEFAULT_CRAWLER_SCHEDULE   int64 = 60   // sec
 	DEFAULT_INDEXER_SCHEDULE   int64 = 60   // sec
+	DEFAULT_SUBMIT_SCHEDULE    int64 = 10   // sec
+	DEFAULT_ARTWORK_SCHEDULE   int64 = 60   // sec
 	DEFAULT_UPDATE_BATCH       int   = 50   // how many podcasts to update per crawler run
 	DEFAULT_INDEX_UPDATE_BATCH int   = 1000 // how many podcasts or episodes to send to elasicsearch each batch
 	MAX_ERRORS                 int   = 4
+	RATE_SAMPLES               int   = 10     // how many recent episodes are used to estimate the publishing interval
+	RATE_CHECKS_PER_INTERVAL   int   = 24     // how often a feed is checked within its publishing interval
+	RATE_HISTORY               int   = 10     // how many rate changes are kept per podcast
+	DORMANT_PERIOD             int   = 129600 // min. (90 days) without a new episode before a podcast is considered dormant
+	CRAWL_HISTORY              int   = 100    // how many crawl attempts are kept per podcast
+	PROBE_BATCH                int   = 10     // how many suspended podcasts are probed per crawler run
+	DEFER_RATE                 int   = 15     // min., a crawl deferred because the host is busy is tried again after that
 	SEARCH_REVISION            int   = 1
+
+	SUBMISSION_ACCEPTED  string = "accepted"
+	SUBMISSION_DUPLICATE string = "duplicate"
+	SUBMISSION_REJECTED  string = "rejected"
+
+	BULK_ADDED     string = "added"
+	BULK_DUPLICATE string = "duplicate"
+	BULK_INVALID   string = "invalid"
+	BULK_ERROR     string = "error"
+
+	JOB_QUEUED   string = "queued"
+	JOB_CRAWLING string = "crawling"
+	JOB_CRAWLED  string = "crawled" // in the index, not searchable yet
+	JOB_INDEXED  string = "indexed"
+	JOB_FAILED   string = "failed"
+
+	SUBMIT_JOB_BATCH   int   = 10                 // how many submissions are processed per run
+	SUBMIT_JOB_TIMEOUT int64 = 600                // sec, a job crawling for longer is picked up again
+	CRAWL_TIMEOUT      int64 = 240                // sec, a crawl is aborted after that, retries and waits for the host included
+	CRAWL_LEASE        int64 = CRAWL_TIMEOUT + 60 // sec, a crawl lease not released by then is reclaimed, covers the writes after the fetch
+
+	ARTWORK_BATCH   int   = 10      // how many podcasts get their artwork processed per run
+	ARTWORK_LEASE   int64 = 600     // sec, artwork still in progress by then is picked up again
+	ARTWORK_REFRESH int64 = 2592000 // sec (30 days), the image behind the same url might have changed
+	ARTWORK_RETRY   int64 = 86400   // sec, broken artwork is tried again after that
 )
 
 type (
@@ -21,6 +55,43 @@ type (
 		Errors     int    `json:errors`
 		Created    int64  `json:"created"`
 		Updated    int64  `json:"updated"`
+
+		// HTTP cache validators of the last successful crawl
+		ETag         string `json:"etag"`
+		LastModified string `json:"last_modified"`
+		Size         int64  `json:"size"` // size of the feed in bytes
+
+		// adaptive scheduling
+		RateHistory []RateChange `json:"rate_history"`
+		FixedRate   bool         `json:"fixed_rate"` // set by an admin, not adjusted to the publishing cadence
+
+		// suspension, a suspended podcast (Errors > MAX_ERRORS) is probed once in a while
+		FailingSince int64 `json:"failing_since"` // first error of the current streak, 0 if healthy
+		Dead         bool  `json:"dead"`          // failing for too long, not probed anymore
+
+		// identity, see util.CanonicalUrl
+		Canonical  string   `json:"canonical"`
+		Aliases    []string `json:"aliases"`     // canonical urls of merged duplicates or former feed urls
+		ContentKey string   `json:"content_key"` // channel link and title, used to detect duplicates
+
+		// crawl lease, see IndexClaim
+		LeaseOwner   string `json:"lease_owner"`   // the crawler instance working on the podcast
+		LeaseExpires int64  `json:"lease_expires"` // a lease not released by then was abandoned
+	}
+
+	PodcastIndexPage struct {
+		Page    int            `json:"page"`
+		Size    int            `json:"size"`
+		Total   int            `json:"total"`
+		Entries []PodcastIndex `json:"entries"`
+	}
+
+	RateChange struct {
+		Rate     int   `json:"rate"`     // new update rate in minutes
+		Interval int   `json:"interval"` // observed publishing interval in minutes
+		Samples  int   `json:"samples"`  // number of episodes the interval is based on
+		Dormant  bool  `json:"dormant"`
+		Created  int64 `json:"created"`
 	}
 
 	/*
@@ -42,7 +113,19 @@ type (
 		ImageUrl    string `json:"image_url"`
 		OwnerName   string `json:"owner_name"`
 		OwnerEmail  string `json:"owner_email"`
-		Tags        string `json:"tags"`
+		Tags        string `json:"tags"` // itunes categories, comma separated
+		Explicit    bool   `json:"explicit"`
+		Type        string `json:"type"`
+		Block       bool   `json:"block"`
+		Complete    bool   `json:"complete"`
+
+		// podcasting 2.0 namespace
+		PodcastGuid string    `json:"podcast_guid"`
+		Locked      bool      `json:"locked"`
+		LockedOwner string    `json:"locked_owner"`
+		Funding     []Funding `json:"funding"`
+		Persons     []Person  `json:"persons"`
+		Value       *Value    `json:"value"`
 
 		// internal admin stuff
 
@@ -51,6 +134,12 @@ type (
 		Score3  int64 `json:"score3"`
 		Version int   `json:"version"`
 
+		MergedInto string `json:"merged_into"` // uid of the podcast this duplicate was merged into
+
+		Artwork      string `json:"artwork"` // image url the thumbnails were made from, empty if there are none
+		ArtworkError string `json:"artwork_error"`
+		ArtworkNext  int64  `json:"artwork_next"` // when the artwork is processed again
+
 		Created int64 `json:"created"`
 		Updated int64 `json:"updated"`
 	}
@@ -65,14 +154,90 @@ type (
 		Published   int64  `jsonapi:"attr,published"`
 		Language    string `jsonapi:"attr,language"`
 		ImageUrl    string `jsonapi:"attr,image_url"`
+		Artwork     string `jsonapi:"attr,artwork"` // thumbnails at <artwork>/<size>, empty if there are none
 		OwnerName   string `jsonapi:"attr,owner_name"`
 		OwnerEmail  string `jsonapi:"attr,owner_email"`
+		Tags        string `jsonapi:"attr,tags"`
+		Explicit    bool   `jsonapi:"attr,explicit"`
+		Type        string `jsonapi:"attr,type"`
+		Block       bool   `jsonapi:"attr,block"`
+		Complete    bool   `jsonapi:"attr,complete"`
+
+		PodcastGuid string    `jsonapi:"attr,podcast_guid"`
+		Locked      bool      `jsonapi:"attr,locked"`
+		Funding     []Funding `jsonapi:"attr,funding"`
+		Persons     []Person  `jsonapi:"attr,persons"`
+		Value       *Value    `jsonapi:"attr,value"`
 
 		Episodes []*Episode `jsonapi:"relation,episodes"`
 	}
 
+	CrawlHistory struct {
+		Uid      string `json:"uid"`
+		Feed     string `json:"feed"`
+		Status   int    `json:"status"` // HTTP status, 0 if there was no response
+		Bytes    int64  `json:"bytes"`
+		Duration int64  `json:"duration"` // msec.
+		Error    string `json:"error"`
+		Class    string `json:"class"` // see feed.Classify, empty for a complete crawl
+		Added    int    `json:"added"`
+		Updated  int    `json:"updated"`
+		Removed  int    `json:"removed"`
+		Created  int64  `json:"created"`
+	}
+
+	CrawlHistoryPage struct {
+		Uid     string         `json:"uid"`
+		Page    int            `json:"page"`
+		Size    int            `json:"size"`
+		Total   int            `json:"total"`
+		Entries []CrawlHistory `json:"entries"`
+	}
+
+	AdminAudit struct {
+		User    string `json:"user"`
+		Action  string `json:"action"`
+		Uid     string `json:"uid"`
+		Detail  string `json:"detail"`
+		Created int64  `json:"created"`
+	}
+
+	Submission struct {
+		Id     string `jsonapi:"primary,submission"`
+		Status string `jsonapi:"attr,status"` // accepted | duplicate | rejected
+		Reason string `jsonapi:"attr,reason"`
+		Feed   string `jsonapi:"attr,feed"`
+		Uid    string `jsonapi:"attr,uid"` // the new or existing podcast
+	}
+
+	BulkOutcome struct {
+		Feed   string `json:"feed"`
+		Status string `json:"status"` // added | duplicate | invalid | error
+		Uid    string `json:"uid"`
+		Reason string `json:"reason"`
+	}
+
+	SubmitJob struct {
+		Id      string `jsonapi:"primary,submit_job"`
+		Feed    string `jsonapi:"attr,feed"`
+		Status  string `jsonapi:"attr,status"` // queued | crawling | crawled | indexed | failed
+		Reason  string `jsonapi:"attr,reason"`
+		Uid     string `jsonapi:"attr,uid"` // the podcast, once it is known
+		Created int64  `jsonapi:"attr,created"`
+		Updated int64  `jsonapi:"attr,updated"`
+	}
+
+	PodcastChange struct {
+		Uid     string `json:"uid"`
+		Field   string `json:"field"`
+		Old     string `json:"old"`
+		New     string `json:"new"`
+		Created int64  `json:"created"`
+	}
+
 	EpisodeMetadata struct {
 		Uid         string `json:"uid"`
+		Guid        string `json:"guid"`
 		Title       string `json:"title"`
 		Url         string `json:"url"`
 		Description string `json:"description"`
@@ -82,11 +247,27 @@ type (
 		AssetUrl    string `json:"asset_url"`
 		AssetType   string `json:"asset_type"`
 		AssetSize   int    `json:"asset_size"`
+		ImageUrl    string `json:"image_url"`
+		Explicit    bool   `json:"explicit"`
+		Season      int    `json:"season"`
+		Number      int    `json:"number"`
+		EpisodeType string `json:"episode_type"`
+		Block       bool   `json:"block"`
+
+		// podcasting 2.0 namespace
+		Chapters     []Chapter    `json:"chapters"`
+		ChaptersUrl  string       `json:"chapters_url"`
+		ChaptersType string       `json:"chapters_type"`
+		Transcripts  []Transcript `json:"transcripts"`
+		Persons      []Person     `json:"persons"`
+		Soundbites   []Soundbite  `json:"soundbites"`
+		Value        *Value       `json:"value"`
 
 		// internal admin stuff
 
 		PodcastUid string `json:"puid"`
 		Version    int    `json:"version"`
+		Removed    int64  `json:"removed"` // when the episode vanished from the feed
 
 		Created int64 `json:"created"`
 		Updated int64 `json:"updated"`
@@ -104,6 +285,71 @@ type (
 		AssetUrl    string `jsonapi:"attr,asset_url"`
 		AssetType   string `jsonapi:"attr,asset_type"`
 		AssetSize   int    `jsonapi:"attr,asset_size"`
+		ImageUrl    string `jsonapi:"attr,image_url"`
+		Explicit    bool   `jsonapi:"attr,explicit"`
+		Season      int    `jsonapi:"attr,season"`
+		Number      int    `jsonapi:"attr,number"`
+		EpisodeType string `jsonapi:"attr,episode_type"`
+		Block       bool   `jsonapi:"attr,block"`
+		Removed     int64  `jsonapi:"attr,removed"`
+
+		Chapters     []Chapter    `jsonapi:"attr,chapters"`
+		ChaptersUrl  string       `jsonapi:"attr,chapters_url"`
+		ChaptersType string       `jsonapi:"attr,chapters_type"`
+		Transcripts  []Transcript `jsonapi:"attr,transcripts"`
+		Persons      []Person     `jsonapi:"attr,persons"`
+		Soundbites   []Soundbite  `jsonapi:"attr,soundbites"`
+		Value        *Value       `jsonapi:"attr,value"`
+	}
+
+	Chapter struct {
+		Start string `json:"start"`
+		Title string `json:"title"`
+		Href  string `json:"href"`
+		Image string `json:"image"`
+	}
+
+	Transcript struct {
+		Url      string `json:"url"`
+		Type     string `json:"type"`
+		Language string `json:"language"`
+		Rel      string `json:"rel"`
+	}
+
+	Funding struct {
+		Url  string `json:"url"`
+		Text string `json:"text"`
+	}
+
+	Person struct {
+		Name  string `json:"name"`
+		Role  string `json:"role"`
+		Group string `json:"group"`
+		Image string `json:"image"`
+		Href  string `json:"href"`
+	}
+
+	Soundbite struct {
+		Start    string `json:"start"`
+		Duration string `json:"duration"`
+		Title    string `json:"title"`
+	}
+
+	Value struct {
+		Type       string           `json:"type"`
+		Method     string           `json:"method"`
+		Suggested  string           `json:"suggested"`
+		Recipients []ValueRecipient `json:"recipients"`
+	}
+
+	ValueRecipient struct {
+		Name        string `json:"name"`
+		Type        string `json:"type"`
+		Address     string `json:"address"`
+		Split       int    `json:"split"`
+		Fee         bool   `json:"fee"`
+		CustomKey   string `json:"custom_key"`
+		CustomValue string `json:"custom_value"`
 	}
 
 	SearchTerm struct {
@@ -48,6 +48,19 @@ func PostJson(url string, body interface{}, response interface{}) error {
 	return json.NewDecoder(r.Body).Decode(response)
 }
 
+func Delete(url string) error {
+	r, err := goreq.Request{
+		Method: "DELETE",
+		Uri:    url,
+	}.Do()
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	return nil
+}
+
 func PrettyPrintJson(target interface{}) {
 	b, err := json.Marshal(target)
 	if err != nil {
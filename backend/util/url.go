@@ -0,0 +1,114 @@
+package util
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrInvalidUrl = errors.New("invalid url")
+
+	// query parameters that only track where a click came from
+	trackingParams = map[string]bool{
+		"fbclid": true,
+		"gclid":  true,
+		"mc_cid": true,
+		"mc_eid": true,
+		"_hsenc": true,
+		"_hsmi":  true,
+	}
+
+	// hosts that serve the same feedburner feeds
+	feedburnerHosts = map[string]bool{
+		"feeds.feedburner.com":  true,
+		"feeds2.feedburner.com": true,
+		"feedproxy.google.com":  true,
+	}
+)
+
+// NormalizeUrl cleans up a feed url but keeps it fetchable: lower case scheme and host,
+// no default port, fragment or tracking parameters, podcast app schemes like feed:// are
+// replaced with http:// and feedburner proxies are mapped to feeds.feedburner.com
+func NormalizeUrl(raw string) (string, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return "", ErrInvalidUrl
+	}
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", ErrInvalidUrl
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "feed", "itpc", "pcast", "podcast":
+		u.Scheme = "http"
+	case "https":
+		u.Scheme = "https"
+	default:
+		return "", ErrInvalidUrl
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Host), ".")
+	if u.Scheme == "http" {
+		host = strings.TrimSuffix(host, ":80")
+	} else {
+		host = strings.TrimSuffix(host, ":443")
+	}
+	if host == "" {
+		return "", ErrInvalidUrl
+	}
+	u.Host = host
+	u.Fragment = ""
+
+	q := u.Query()
+	for k := range q {
+		if strings.HasPrefix(strings.ToLower(k), "utm_") || trackingParams[strings.ToLower(k)] {
+			q.Del(k)
+		}
+	}
+
+	if feedburnerHosts[u.Host] {
+		u.Host = "feeds.feedburner.com"
+		q.Del("format")
+		q.Del("fmt")
+	}
+	u.RawQuery = q.Encode()
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	return u.String(), nil
+}
+
+// CanonicalUrl returns the identity of a feed url, e.g. http://www.example.com/feed/ and
+// https://example.com/feed are the same feed. The result is not fetchable as it has no scheme.
+func CanonicalUrl(raw string) (string, error) {
+	n, err := NormalizeUrl(raw)
+	if err != nil {
+		return "", err
+	}
+
+	u, _ := url.Parse(n)
+
+	canonical := strings.TrimPrefix(u.Host, "www.") + strings.TrimRight(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		canonical = canonical + "?" + u.RawQuery
+	}
+
+	return canonical, nil
+}
+
+// CanonicalUID is the uid of a new podcast, based on the canonical feed url
+func CanonicalUID(raw string) string {
+	canonical, err := CanonicalUrl(raw)
+	if err != nil {
+		return UID(raw)
+	}
+	return UID(canonical)
+}
@@ -9,6 +9,7 @@ import (
 	"math/rand"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -79,3 +80,52 @@ func UUID() (string, error) {
 func NormalizeSearchString(s string) string {
 	return strings.Replace(strings.ToLower(strings.Trim(s, " ")), " ", "+", -1)
 }
+
+// Tracker counts work in progress like a WaitGroup. Once Close was called it refuses new
+// work, so that work starting never races with waiting for it.
+type Tracker struct {
+	sync.Mutex
+	wg      sync.WaitGroup
+	closing bool
+}
+
+// Add registers new work, false if the tracker is closing and the work must not start
+func (t *Tracker) Add() bool {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.closing {
+		return false
+	}
+	t.wg.Add(1)
+	return true
+}
+
+func (t *Tracker) Done() {
+	t.wg.Done()
+}
+
+// Close refuses new work and waits for the work in progress, false if it took longer than d
+func (t *Tracker) Close(d time.Duration) bool {
+	t.Lock()
+	t.closing = true
+	t.Unlock()
+
+	return WaitTimeout(&t.wg, d)
+}
+
+// WaitTimeout waits for the group, false if it took longer than d
+func WaitTimeout(wg *sync.WaitGroup, d time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
@@ -0,0 +1,22 @@
+package backend
+
+import (
+	"bytes"
+	"reflect"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// SameValues compares two values the way the datastore keeps them. A round trip through bson
+// loses the difference between nil and empty slices on every level, so the bson forms are compared.
+func SameValues(a interface{}, b interface{}) bool {
+	ba, err := bson.Marshal(bson.M{"v": a})
+	if err != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	bb, err := bson.Marshal(bson.M{"v": b})
+	if err != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return bytes.Equal(ba, bb)
+}
@@ -1,10 +1,9 @@
 #!/bin/bash
 
-function post {
-	echo "Submitting '$1'"
-	curl -i -H 'Content-Type: application/json' -d "{\"feed\":\"$1\"}" $2/api/1/submit
-	echo ""
-}
+# upload.sh <file> <endpoint>
+#
+# Submits all feeds of an OPML file or a text file with one url per line in one request.
+# The admin credentials are taken from ADMIN_USER and ADMIN_PASSWORD.
 
 filename=$1
 endpoint=$2
@@ -12,10 +11,7 @@ endpoint=$2
 if [ -f $filename ]
 then
 
-  while read line
-  do
-	  post $line $endpoint
-	  #sleep 1
-  done < $filename
+  curl -i -u "${ADMIN_USER:-admin}:$ADMIN_PASSWORD" -H 'Content-Type: text/plain' --data-binary @$filename $endpoint/api/1/admin/import
+  echo ""
 
 fi
@@ -27,3 +27,6 @@ echo "Building the migrations"
 
 cd $MINDCAST_SRC/tools/migrations
 go build migration_001.go
+go build migration_002.go
+go build migration_003.go
+go build migration_004.go
@@ -0,0 +1,54 @@
+package crawler
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/artwork"
+	"github.com/mindcastio/mindcastio/backend/feed"
+	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/metrics"
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+// ScheduleArtwork makes thumbnails for podcasts with new or changed artwork, and
+// refreshes old ones. It stops between podcasts once ctx is cancelled.
+func ScheduleArtwork(ctx context.Context) {
+
+	count := 0
+	for _, p := range backend.ArtworkClaim(backend.ARTWORK_BATCH) {
+		if ctx.Err() != nil {
+			break // the claim expires, the next run picks them up
+		}
+		processArtwork(&p)
+		count++
+	}
+
+	if count > 0 {
+		logger.Log("crawler.schedule_artwork.done", strconv.FormatInt((int64)(count), 10))
+		metrics.Count("crawler.artwork", count)
+	}
+}
+
+func processArtwork(p *backend.PodcastMetadata) {
+
+	start := time.Now()
+
+	err := artwork.Process(p.Uid, p.ImageUrl)
+	if feed.IsDeferred(err) {
+		return // tried again once the claim expired
+	}
+	if err != nil {
+		logger.Warn("crawler.artwork.failed", p.Uid, p.ImageUrl, err.Error())
+		metrics.Count("crawler.artwork.failed", 1)
+	} else {
+		metrics.Histogram("crawler.artwork.duration", (float64)(util.ElapsedTimeSince(start)))
+	}
+
+	e := backend.ArtworkUpdate(p.Uid, p.ImageUrl, err)
+	if e != nil {
+		logger.Error("crawler.artwork.error", e, p.Uid)
+	}
+}
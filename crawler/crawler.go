@@ -1,58 +1,110 @@
 package crawler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
+	"net/url"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/feed"
 
 	"github.com/mindcastio/mindcastio/backend/datastore"
+	"github.com/mindcastio/mindcastio/backend/environment"
 	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/messaging"
 	"github.com/mindcastio/mindcastio/backend/metrics"
 	"github.com/mindcastio/mindcastio/backend/util"
 )
 
-func SchedulePodcastCrawling() {
+var (
+	ErrPodcastNotFound = errors.New("podcast not found in index")
+	ErrPodcastLeased   = errors.New("podcast is being crawled")
+
+	// Instance identifies this crawler process, it owns the crawl leases it claims
+	Instance = instanceId()
+)
+
+func instanceId() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "crawler"
+	}
+	return host + "-" + strconv.Itoa(os.Getpid())
+}
+
+// SchedulePodcastCrawling crawls the podcasts that are due. Once ctx is cancelled no new crawls
+// are started, the ones still fetching are cancelled before they write anything.
+func SchedulePodcastCrawling(ctx context.Context) {
+	start := time.Now()
 	logger.Log("mindcast.crawler.schedule_podcast_crawling")
 
-	// search for podcasts that are candidates for crawling
+	// search for podcasts that are candidates for crawling, and suspended ones that are due for a probe
 	expired := searchExpiredPodcasts(backend.DEFAULT_UPDATE_BATCH)
+	expired = append(expired, searchSuspendedPodcasts(backend.PROBE_BATCH)...)
 	count := len(expired)
 
 	logger.Log("crawler.schedule_podcast_crawling.scheduling", strconv.FormatInt((int64)(count), 10))
 
 	if count > 0 {
-		for i := 0; i < count; i++ {
-			CrawlPodcastFeed(expired[i].Uid)
-			// FIXME use go routine or not ?
-		}
+		env := environment.GetEnvironment()
+
+		// crawl the batch in parallel but stop before the next run is due
+		stats := crawlAll(ctx, expired, env.CrawlerWorkers(), env.CrawlerHostWorkers(), time.Second*time.Duration(env.CrawlerDeadline()))
+
+		logger.Log("crawler.schedule_podcast_crawling.stats", strconv.FormatInt((int64)(stats.Crawled), 10), strconv.FormatInt((int64)(stats.Failed), 10), strconv.FormatInt((int64)(stats.Skipped), 10), strconv.FormatInt((int64)(stats.Deferred), 10))
+
 		metrics.Count("crawler.scheduled", count)
+		metrics.Count("crawler.run.crawled", stats.Crawled)
+		metrics.Count("crawler.run.failed", stats.Failed)
+		metrics.Count("crawler.run.skipped", stats.Skipped)
+		metrics.Count("crawler.run.deferred", stats.Deferred)
+		metrics.Histogram("crawler.run.duration", (float64)(util.ElapsedTimeSince(start)))
 	}
 
 	logger.Log("crawler.schedule_podcast_crawling.done")
 }
 
-func CrawlPodcastFeed(uid string) {
+// CrawlPodcastFeed fetches the feed and updates podcast and episodes. The caller holds the
+// lease on the podcast, ctx bounds the crawl to the lease, see CRAWL_TIMEOUT.
+func CrawlPodcastFeed(ctx context.Context, uid string) error {
 
 	start_1 := time.Now()
 	logger.Log("crawl_podcast_feed", uid)
 
 	idx := backend.IndexLookup(uid)
 	if idx == nil {
-		logger.Error("crawl_podcast_feed.error.1", nil, uid)
-		metrics.Error("crawl_podcast_feed.error", "", []string{uid})
-		return
+		logger.Error("crawl_podcast_feed.error.1", ErrPodcastNotFound, uid)
+		metrics.Error("crawl_podcast_feed.error", ErrPodcastNotFound.Error(), []string{uid})
+		return ErrPodcastNotFound
 	}
 
 	// HINT: ignore the fact that the item might be disables idx.erros > ...
 
 	// fetch the podcast feed
 	start_2 := time.Now()
-	podcast, err := ParsePodcastFeed(idx.Feed)
+	podcast, response, err := FetchPodcastFeed(ctx, idx)
 	metrics.Histogram("crawler.parse.duration", (float64)(util.ElapsedTimeSince(start_2)))
 
+	if ctx.Err() != nil {
+		crawlAborted(idx, response, start_1, ctx.Err())
+		return ctx.Err()
+	}
+
+	if feed.IsDeferred(err) {
+		crawlHistory(idx, response, start_1, err, 0, 0, 0)
+		podcastDefer(idx, err)
+
+		return err
+	}
 	if err != nil {
+		crawlHistory(idx, response, start_1, err, 0, 0, 0)
 		suspended, _ := backend.IndexBackoff(uid)
 
 		if suspended {
@@ -60,28 +112,77 @@ func CrawlPodcastFeed(uid string) {
 			metrics.Error("crawl_podcast_feed.suspended", err.Error(), []string{uid, idx.Feed})
 		}
 
-		return
+		return err
+	}
+
+	// the feed moved, follow it instead of suspending or duplicating the podcast
+	moved := feedMoved(idx, podcast, response)
+	if moved != "" {
+		err = backend.IndexMigrateFeed(uid, moved)
+		if err != nil {
+			logger.Error("crawl_podcast_feed.migrate.error", err, uid, idx.Feed, moved)
+			metrics.Error("crawl_podcast_feed.migrate.error", err.Error(), []string{uid, idx.Feed, moved})
+		} else {
+			logger.Log("crawl_podcast_feed.migrated", uid, idx.Feed, moved)
+			metrics.Count("crawler.migrated", 1)
+
+			if podcast != nil {
+				podcast.Feed = moved
+			}
+		}
+	}
+
+	if response.NotModified() {
+		// nothing changed since the last crawl, just schedule the next one
+		backend.IndexUpdate(uid)
+
+		logger.Log("crawl_podcast_feed.not_modified", uid, idx.Feed)
+		crawlHistory(idx, response, start_1, nil, 0, 0, 0)
+
+		metrics.Count("crawler.not_modified", 1)
+		metrics.Count("crawler.not_modified.bytes_saved", (int)(idx.Size))
+		metrics.Count("crawler.count", 1)
+		metrics.Histogram("crawler.duration", (float64)(util.ElapsedTimeSince(start_1)))
+
+		return nil
 	}
 
 	// add to podcast metadata index
-	is_new, err := podcastAdd(podcast)
+	is_new, changed, err := podcastAdd(podcast)
 	if err != nil {
 		logger.Error("crawl_podcast_feed.error.3", err, uid, idx.Feed)
 		metrics.Error("crawl_podcast_feed.error", err.Error(), []string{uid, idx.Feed})
+		crawlHistory(idx, response, start_1, err, 0, 0, 0)
 
-		return
+		return err
+	}
+
+	if response.Truncated {
+		logger.Warn("crawl_podcast_feed.truncated", uid, idx.Feed, strconv.FormatInt((int64)(response.Items), 10))
 	}
 
 	// add to the episodes metadata index
-	count, err := episodesAddAll(podcast)
+	count, updated, removed, err := episodesAddAll(podcast, response.Truncated)
 	if err != nil {
 		logger.Error("crawl_podcast_feed.error.4", err, uid, idx.Feed)
 		metrics.Error("crawl_podcast_feed.error", err.Error(), []string{uid, idx.Feed})
+		crawlHistory(idx, response, start_1, err, 0, 0, 0)
 
-		return
+		return err
 	} else {
 		// update main metadata index
 		backend.IndexUpdate(uid)
+		backend.IndexUpdateValidators(uid, response.ETag, response.LastModified, response.Bytes)
+
+		// the same show might already be known under a different feed url
+		podcastDeduplicate(idx, podcast)
+
+		if updated > 0 || removed > 0 {
+			logger.Log("crawl_podcast_feed.episodes_changed", uid, strconv.FormatInt((int64)(updated), 10), strconv.FormatInt((int64)(removed), 10))
+
+			metrics.Count("index.episodes.updated", updated)
+			metrics.Count("index.episodes.removed", removed)
+		}
 
 		if count > 0 {
 			// update stats and metrics
@@ -95,17 +196,80 @@ func CrawlPodcastFeed(uid string) {
 			}
 		}
 
+		// let the indexer know right away instead of waiting for its next sweep
+		if changed || count > 0 || updated > 0 || removed > 0 {
+			messaging.Publish(messaging.INDEX_SUBJECT, uid, "")
+		}
+
 		logger.Log("crawl_podcast_feed.done", uid, idx.Feed, strconv.FormatInt((int64)(count), 10))
+		crawlHistory(idx, response, start_1, nil, count, updated, removed)
 
 		metrics.Count("crawler.count", 1)
 		metrics.Histogram("crawler.duration", (float64)(util.ElapsedTimeSince(start_1)))
 	}
+
+	return nil
 }
 
-func podcastAdd(podcast *Podcast) (bool, error) {
+// ProbePodcastFeed checks if a suspended feed is back. A cheap HEAD request comes first,
+// only if the server answers the feed is crawled again, which reactivates it if it is valid.
+func ProbePodcastFeed(ctx context.Context, uid string) error {
+
+	start := time.Now()
+	logger.Log("probe_podcast_feed", uid)
+
+	idx := backend.IndexLookup(uid)
+	if idx == nil {
+		logger.Error("probe_podcast_feed.error", ErrPodcastNotFound, uid)
+		return ErrPodcastNotFound
+	}
+
+	response, err := feed.ProbeContext(ctx, feed.DefaultFetcher, idx.Feed)
+	metrics.Count("crawler.probe", 1)
+
+	if ctx.Err() != nil {
+		crawlAborted(idx, response, start, ctx.Err())
+		return ctx.Err()
+	}
+
+	if feed.IsDeferred(err) {
+		crawlHistory(idx, response, start, err, 0, 0, 0)
+		podcastDefer(idx, err)
+
+		return err
+	}
+	if err != nil {
+		crawlHistory(idx, response, start, err, 0, 0, 0)
+		backend.IndexBackoff(uid)
+
+		logger.Warn("probe_podcast_feed.failed", uid, idx.Feed, err.Error())
+		metrics.Count("crawler.probe.failed", 1)
+
+		return err
+	}
+
+	// the crawl resets the errors if the feed is valid, otherwise backs off again
+	err = CrawlPodcastFeed(ctx, uid)
+	if err != nil {
+		logger.Warn("probe_podcast_feed.failed", uid, idx.Feed, err.Error())
+		metrics.Count("crawler.probe.failed", 1)
+
+		return err
+	}
+
+	logger.Log("probe_podcast_feed.reactivated", uid, idx.Feed)
+	metrics.Count("crawler.probe.reactivated", 1)
+
+	return nil
+}
+
+// podcastAdd returns if the podcast is new or its metadata changed
+func podcastAdd(podcast *Podcast) (bool, bool, error) {
 	p := backend.PodcastLookup(podcast.Uid)
 	if p != nil {
-		return false, nil
+		// known podcast, check for changed metadata instead
+		changed, err := podcastUpdate(podcast, p)
+		return false, changed, err
 	}
 
 	ds := datastore.GetDataStore()
@@ -124,12 +288,275 @@ func podcastAdd(podcast *Podcast) (bool, error) {
 	err := podcast_metadata.Insert(&meta)
 
 	if err != nil {
-		return false, err
+		return false, false, err
 	} else {
-		return true, nil
+		return true, true, nil
 	}
 }
 
+func podcastUpdate(podcast *Podcast, p *backend.PodcastMetadata) (bool, error) {
+
+	now := util.Timestamp()
+	meta := podcastDetailsToMetadata(podcast)
+
+	fields := []struct {
+		name    string
+		current *string
+		value   string
+	}{
+		{"title", &p.Title, meta.Title},
+		{"subtitle", &p.Subtitle, meta.Subtitle},
+		{"url", &p.Url, meta.Url},
+		{"feed", &p.Feed, meta.Feed},
+		{"description", &p.Description, meta.Description},
+		{"language", &p.Language, meta.Language},
+		{"image_url", &p.ImageUrl, meta.ImageUrl},
+		{"owner_name", &p.OwnerName, meta.OwnerName},
+		{"owner_email", &p.OwnerEmail, meta.OwnerEmail},
+		{"tags", &p.Tags, meta.Tags},
+		{"type", &p.Type, meta.Type},
+		{"podcast_guid", &p.PodcastGuid, meta.PodcastGuid},
+		{"locked_owner", &p.LockedOwner, meta.LockedOwner},
+	}
+
+	flags := []struct {
+		name    string
+		current *bool
+		value   bool
+	}{
+		{"explicit", &p.Explicit, meta.Explicit},
+		{"block", &p.Block, meta.Block},
+		{"complete", &p.Complete, meta.Complete},
+		{"locked", &p.Locked, meta.Locked},
+	}
+
+	image := p.ImageUrl
+
+	changes := make([]interface{}, 0)
+	for _, f := range fields {
+		if *f.current != f.value {
+			changes = append(changes, &backend.PodcastChange{p.Uid, f.name, *f.current, f.value, now})
+			*f.current = f.value
+		}
+	}
+	for _, f := range flags {
+		if *f.current != f.value {
+			changes = append(changes, &backend.PodcastChange{p.Uid, f.name, strconv.FormatBool(*f.current), strconv.FormatBool(f.value), now})
+			*f.current = f.value
+		}
+	}
+
+	// podcasting 2.0 structures, logged as JSON
+	if !backend.SameValues(p.Funding, meta.Funding) {
+		changes = append(changes, structuredChange(p.Uid, "funding", p.Funding, meta.Funding, now))
+		p.Funding = meta.Funding
+	}
+	if !backend.SameValues(p.Persons, meta.Persons) {
+		changes = append(changes, structuredChange(p.Uid, "persons", p.Persons, meta.Persons, now))
+		p.Persons = meta.Persons
+	}
+	if !backend.SameValues(p.Value, meta.Value) {
+		changes = append(changes, structuredChange(p.Uid, "value", p.Value, meta.Value, now))
+		p.Value = meta.Value
+	}
+
+	if len(changes) == 0 {
+		return false, nil
+	}
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	// reset the version so that the indexer picks it up again
+	p.Version = 0
+	p.Updated = now
+
+	// only the fields taken from the feed, the artwork processing and the indexer update theirs meanwhile
+	update := bson.M{
+		"title":       p.Title,
+		"subtitle":    p.Subtitle,
+		"url":         p.Url,
+		"feed":        p.Feed,
+		"description": p.Description,
+		"language":    p.Language,
+		"imageurl":    p.ImageUrl,
+		"ownername":   p.OwnerName,
+		"owneremail":  p.OwnerEmail,
+		"tags":        p.Tags,
+		"explicit":    p.Explicit,
+		"type":        p.Type,
+		"block":       p.Block,
+		"complete":    p.Complete,
+		"podcastguid": p.PodcastGuid,
+		"locked":      p.Locked,
+		"lockedowner": p.LockedOwner,
+		"funding":     p.Funding,
+		"persons":     p.Persons,
+		"value":       p.Value,
+		"version":     p.Version,
+		"updated":     p.Updated,
+	}
+
+	if p.ImageUrl != image {
+		p.ArtworkNext = 0 // new thumbnails right away
+		update["artworknext"] = p.ArtworkNext
+	}
+
+	podcast_metadata := ds.Collection(datastore.PODCASTS_COL)
+	err := podcast_metadata.Update(bson.M{"uid": p.Uid}, bson.M{"$set": update})
+	if err != nil {
+		return false, err
+	}
+
+	podcast_changes := ds.Collection(datastore.PODCAST_CHANGES_COL)
+	err = podcast_changes.Insert(changes...)
+	if err != nil {
+		logger.Error("podcast_update.error", err, p.Uid)
+	}
+
+	logger.Log("podcast_update.changed", p.Uid, strconv.FormatInt((int64)(len(changes)), 10))
+	metrics.Count("index.podcasts.changed", 1)
+
+	return true, nil
+}
+
+const (
+	DUPLICATE_ENCLOSURES int = 3    // how many of the latest enclosures are compared to detect duplicates
+	EPISODE_BATCH        int = 1000 // episodes written with one bulk request
+)
+
+// podcastDeduplicate merges podcasts with the same channel link and title that share one of the
+// latest enclosures. The older podcast survives, the urls of the other one become its aliases.
+func podcastDeduplicate(idx *backend.PodcastIndex, podcast *Podcast) {
+
+	title := strings.ToLower(strings.TrimSpace(podcast.Title))
+	if title == "" {
+		return
+	}
+
+	link, err := util.CanonicalUrl(podcast.Url)
+	if err != nil {
+		link = strings.TrimSpace(podcast.Url)
+	}
+
+	key := util.Fingerprint(link, title)
+	if key != idx.ContentKey {
+		backend.IndexUpdateContentKey(idx.Uid, key)
+	}
+
+	enclosures := make([]string, 0, DUPLICATE_ENCLOSURES)
+	for i := range podcast.Episodes {
+		if len(enclosures) == DUPLICATE_ENCLOSURES {
+			break
+		}
+		if podcast.Episodes[i].Content.Url != "" {
+			enclosures = append(enclosures, podcast.Episodes[i].Content.Url)
+		}
+	}
+
+	dup := backend.IndexFindDuplicate(idx.Uid, key, enclosures)
+	if dup == nil {
+		return
+	}
+
+	survivor, merged := dup.Uid, idx.Uid
+	if idx.Created < dup.Created {
+		survivor, merged = idx.Uid, dup.Uid
+	}
+
+	err = backend.IndexMerge(survivor, merged)
+	if err != nil {
+		logger.Error("crawl_podcast_feed.merge.error", err, survivor, merged)
+		metrics.Error("crawl_podcast_feed.merge.error", err.Error(), []string{survivor, merged})
+		return
+	}
+
+	logger.Log("crawl_podcast_feed.merged", survivor, merged)
+	messaging.Publish(messaging.INDEX_SUBJECT, merged, "")
+}
+
+// podcastDefer reschedules a crawl that was skipped for politeness, it does not count as an error.
+// A busy host is tried again soon, a feed disallowed by robots.txt with its normal rate.
+func podcastDefer(idx *backend.PodcastIndex, err error) {
+	delay := backend.DEFER_RATE + util.Random(backend.DEFER_RATE)
+	if err == feed.ErrDisallowed {
+		delay = idx.UpdateRate
+	}
+
+	e := backend.IndexDefer(idx.Uid, delay)
+	if e != nil {
+		logger.Error("crawl_podcast_feed.defer.error", e, idx.Uid)
+	}
+
+	logger.Log("crawl_podcast_feed.deferred", idx.Uid, idx.Feed, err.Error())
+	metrics.Count("crawler.deferred", 1)
+}
+
+// crawlAborted records a crawl that ran out of time or was cancelled. Running out of time counts
+// as an error, otherwise a feed too slow for the time budget is claimed again on every run.
+// A cancelled crawl is no fault of the feed, it is still due for the next run.
+func crawlAborted(idx *backend.PodcastIndex, response *feed.Response, start time.Time, err error) {
+	crawlHistory(idx, response, start, err, 0, 0, 0)
+	if err != context.DeadlineExceeded {
+		return
+	}
+
+	suspended, e := backend.IndexBackoff(idx.Uid)
+	if e != nil {
+		logger.Error("crawl_podcast_feed.backoff.error", e, idx.Uid)
+	}
+	if suspended {
+		logger.Error("crawl_podcast_feed.suspended", err, idx.Uid, idx.Feed)
+		metrics.Error("crawl_podcast_feed.suspended", err.Error(), []string{idx.Uid, idx.Feed})
+	}
+
+	logger.Warn("crawl_podcast_feed.timeout", idx.Uid, idx.Feed)
+	metrics.Count("crawler.timeout", 1)
+}
+
+// crawlHistory records the outcome of a crawl attempt
+func crawlHistory(idx *backend.PodcastIndex, response *feed.Response, start time.Time, err error, added int, updated int, removed int) {
+	h := backend.CrawlHistory{idx.Uid, idx.Feed, 0, 0, util.ElapsedTimeSince(start), "", "", added, updated, removed, util.Timestamp()}
+	if response != nil {
+		h.Status = response.Status
+		h.Bytes = response.Bytes
+	}
+	if err != nil {
+		h.Error = err.Error()
+	}
+
+	h.Class = feed.Classify(err, response)
+	if h.Class != "" {
+		metrics.Count("crawler.class."+h.Class, 1)
+	}
+
+	e := backend.CrawlHistoryAdd(&h)
+	if e != nil {
+		logger.Error("crawl_podcast_feed.history.error", e, idx.Uid)
+	}
+
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	messaging.Publish(messaging.CRAWLED_SUBJECT, idx.Uid, status)
+}
+
+// feedMoved returns the new feed url if the publisher announced one with
+// itunes:new-feed-url or the feed was permanently redirected
+func feedMoved(idx *backend.PodcastIndex, podcast *Podcast, response *feed.Response) string {
+	if podcast != nil && podcast.NewFeedUrl != "" && podcast.NewFeedUrl != idx.Feed {
+		u, err := url.Parse(podcast.NewFeedUrl)
+		if err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+			return podcast.NewFeedUrl
+		}
+	}
+	if response.Moved != "" && response.Moved != idx.Feed {
+		return response.Moved
+	}
+	return ""
+}
+
 func podcastUpdateTimestamp(podcast *Podcast) (bool, error) {
 
 	ds := datastore.GetDataStore()
@@ -151,8 +578,8 @@ func podcastUpdateTimestamp(podcast *Podcast) (bool, error) {
 			p.Published = podcast.Published
 		}
 
-		// update the DB
-		err := podcast_metadata.Update(bson.M{"uid": podcast.Uid}, &p)
+		// update the DB, only the timestamps
+		err := podcast_metadata.Update(bson.M{"uid": podcast.Uid}, bson.M{"$set": bson.M{"updated": p.Updated, "published": p.Published}})
 		if err != nil {
 			return false, err
 		}
@@ -161,61 +588,198 @@ func podcastUpdateTimestamp(podcast *Podcast) (bool, error) {
 	return true, nil
 }
 
-func episodeAdd(episode *Episode, puid string) (bool, error) {
-	e := backend.EpisodeLookup(episode.Uid)
-	if e != nil {
-		return false, nil
-	}
+// episodesAddAll reconciles the episodes in the feed with the stored ones: new episodes are added,
+// changed ones updated and episodes that vanished from the feed are marked as removed.
+// A truncated feed does not list all episodes, nothing is removed then.
+func episodesAddAll(podcast *Podcast, truncated bool) (int, int, int, error) {
 
 	ds := datastore.GetDataStore()
 	defer ds.Close()
 
 	episodes_metadata := ds.Collection(datastore.EPISODES_COL)
 
-	meta := episodeDetailsToMetadata(episode, puid)
-	err := episodes_metadata.Insert(&meta)
+	stored := []backend.EpisodeMetadata{}
+	err := episodes_metadata.Find(bson.M{"podcastuid": podcast.Uid}).All(&stored)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	known := make(map[string]*backend.EpisodeMetadata, len(stored))
+	for i := range stored {
+		known[stored[i].Uid] = &stored[i]
+	}
+
+	now := util.Timestamp()
+	seen := make(map[string]bool, len(podcast.Episodes))
+	inserts := []interface{}{}
+	updates := []interface{}{} // pairs of selector and document
+	added, updated, removed := 0, 0, 0
+
+	for i := range podcast.Episodes {
+		episode := &podcast.Episodes[i]
+		if seen[episode.Uid] {
+			continue // same episode listed twice in the feed
+		}
+		seen[episode.Uid] = true
+
+		e, found := known[episode.Uid]
+		if !found {
+			if episode.Published == 0 {
+				episode.Published = now // unknown date, use the time it was first seen
+			}
 
+			inserts = append(inserts, episodeDetailsToMetadata(episode, podcast.Uid))
+			added++
+		} else if episodeUpdate(e, episode, now) {
+			updates = append(updates, bson.M{"uid": e.Uid}, e)
+			updated++
+		}
+	}
+
+	if !truncated {
+		for uid, e := range known {
+			if seen[uid] || e.Removed != 0 {
+				continue
+			}
+
+			// vanished from the feed
+			e.Removed = now
+			e.Updated = now
+			e.Version = 0 // removes it from the search index
+
+			updates = append(updates, bson.M{"uid": uid}, e)
+			removed++
+		}
+	}
+
+	err = episodesBulk(episodes_metadata, inserts, updates)
 	if err != nil {
-		return false, err
-	} else {
-		return true, nil
+		return 0, 0, 0, err
 	}
+
+	return added, updated, removed, nil
 }
 
-func episodesAddAll(podcast *Podcast) (int, error) {
-	count := 0
+// episodesBulk writes the changes in batches of EPISODE_BATCH instead of one request per episode
+func episodesBulk(c *mgo.Collection, inserts []interface{}, updates []interface{}) error {
+
+	for start := 0; start < len(inserts); start += EPISODE_BATCH {
+		end := start + EPISODE_BATCH
+		if end > len(inserts) {
+			end = len(inserts)
+		}
 
-	for i := 0; i < len(podcast.Episodes); i++ {
-		added, err := episodeAdd(&podcast.Episodes[i], podcast.Uid)
+		bulk := c.Bulk()
+		bulk.Insert(inserts[start:end]...)
+		_, err := bulk.Run()
 		if err != nil {
-			return 0, err
+			return err
 		}
-		if added {
-			count++
+	}
+
+	for start := 0; start < len(updates); start += 2 * EPISODE_BATCH {
+		end := start + 2*EPISODE_BATCH
+		if end > len(updates) {
+			end = len(updates)
+		}
+
+		bulk := c.Bulk()
+		bulk.Update(updates[start:end]...)
+		_, err := bulk.Run()
+		if err != nil {
+			return err
 		}
 	}
-	return count, nil
+
+	return nil
 }
 
-func searchExpiredPodcasts(limit int) []backend.PodcastIndex {
+// episodeUpdate copies changed fields from the feed into the stored episode
+func episodeUpdate(e *backend.EpisodeMetadata, episode *Episode, now int64) bool {
 
-	ds := datastore.GetDataStore()
-	defer ds.Close()
+	meta := episodeDetailsToMetadata(episode, e.PodcastUid)
+	if meta.Published == 0 {
+		meta.Published = e.Published // unknown date, keep the first seen time
+	}
 
-	main_index := ds.Collection(datastore.META_COL)
+	changed := e.Removed != 0 ||
+		e.Guid != meta.Guid ||
+		e.Title != meta.Title ||
+		e.Url != meta.Url ||
+		e.Description != meta.Description ||
+		e.Published != meta.Published ||
+		e.Duration != meta.Duration ||
+		e.Author != meta.Author ||
+		e.AssetUrl != meta.AssetUrl ||
+		e.AssetType != meta.AssetType ||
+		e.AssetSize != meta.AssetSize ||
+		e.ImageUrl != meta.ImageUrl ||
+		e.Explicit != meta.Explicit ||
+		e.Season != meta.Season ||
+		e.Number != meta.Number ||
+		e.EpisodeType != meta.EpisodeType ||
+		e.Block != meta.Block ||
+		e.ChaptersUrl != meta.ChaptersUrl ||
+		e.ChaptersType != meta.ChaptersType ||
+		!backend.SameValues(e.Chapters, meta.Chapters) ||
+		!backend.SameValues(e.Transcripts, meta.Transcripts) ||
+		!backend.SameValues(e.Persons, meta.Persons) ||
+		!backend.SameValues(e.Soundbites, meta.Soundbites) ||
+		!backend.SameValues(e.Value, meta.Value)
+
+	if !changed {
+		return false
+	}
 
-	results := []backend.PodcastIndex{}
-	q := bson.M{"next": bson.M{"$lte": util.Timestamp()}, "errors": bson.M{"$lte": backend.MAX_ERRORS}}
+	// keep the identity and creation date, take everything else from the feed
+	meta.Created = e.Created
+	meta.Updated = now
+	*e = *meta
 
-	if limit <= 0 {
-		// return all
-		main_index.Find(q).All(&results)
-	} else {
-		// with a limit
-		main_index.Find(q).Limit(limit).All(&results)
+	return true
+}
+
+func structuredChange(uid string, field string, old interface{}, new interface{}, now int64) *backend.PodcastChange {
+	o, _ := json.Marshal(old)
+	n, _ := json.Marshal(new)
+	return &backend.PodcastChange{uid, field, string(o), string(n), now}
+}
+
+// CrawlLeased crawls a single podcast outside of the scheduled runs. It holds the lease on the
+// podcast like they do, ErrPodcastLeased means another crawl of it is in progress.
+func CrawlLeased(ctx context.Context, uid string) error {
+	claimed := backend.IndexClaim(Instance, bson.M{"uid": uid}, 1)
+	if len(claimed) == 0 {
+		if backend.IndexLookup(uid) == nil {
+			return ErrPodcastNotFound
+		}
+		return ErrPodcastLeased
+	}
+	defer crawlRelease(&claimed[0])
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(backend.CRAWL_TIMEOUT))
+	defer cancel()
+
+	return CrawlPodcastFeed(ctx, uid)
+}
+
+func crawlRelease(idx *backend.PodcastIndex) {
+	err := backend.IndexRelease(idx)
+	if err != nil {
+		logger.Error("crawler.release.error", err, idx.Uid)
 	}
+}
+
+// searchExpiredPodcasts leases podcasts that are due for a crawl to this instance
+func searchExpiredPodcasts(limit int) []backend.PodcastIndex {
+	q := bson.M{"next": bson.M{"$lte": util.Timestamp()}, "errors": bson.M{"$lte": backend.MAX_ERRORS}}
+	return backend.IndexClaim(Instance, q, limit)
+}
 
-	return results
+// searchSuspendedPodcasts leases suspended podcasts that are due for a probe, dead ones are left alone
+func searchSuspendedPodcasts(limit int) []backend.PodcastIndex {
+	q := bson.M{"next": bson.M{"$lte": util.Timestamp()}, "errors": bson.M{"$gt": backend.MAX_ERRORS}, "dead": bson.M{"$ne": true}}
+	return backend.IndexClaim(Instance, q, limit)
 }
 
 func podcastDetailsToMetadata(podcast *Podcast) *backend.PodcastMetadata {
@@ -231,11 +795,25 @@ func podcastDetailsToMetadata(podcast *Podcast) *backend.PodcastMetadata {
 		podcast.Image,
 		podcast.Owner.Name,
 		podcast.Owner.Email,
-		"",
+		strings.Join(podcast.Categories, ","),
+		podcast.Explicit,
+		podcast.Type,
+		podcast.Block,
+		podcast.Complete,
+		podcast.PodcastGuid,
+		podcast.Locked,
+		podcast.LockedOwner,
+		podcast.Funding,
+		podcast.Persons,
+		podcast.Value,
 		0,
 		0,
 		0,
 		0,
+		"",
+		"",
+		"",
+		0,
 		util.Timestamp(),
 		0,
 	}
@@ -245,6 +823,7 @@ func podcastDetailsToMetadata(podcast *Podcast) *backend.PodcastMetadata {
 func episodeDetailsToMetadata(episode *Episode, puid string) *backend.EpisodeMetadata {
 	meta := backend.EpisodeMetadata{
 		episode.Uid,
+		episode.Guid,
 		episode.Title,
 		episode.Url,
 		episode.Description,
@@ -254,8 +833,22 @@ func episodeDetailsToMetadata(episode *Episode, puid string) *backend.EpisodeMet
 		episode.Content.Url,
 		episode.Content.Type,
 		episode.Content.Size,
+		episode.Image,
+		episode.Explicit,
+		episode.Season,
+		episode.Number,
+		episode.EpisodeType,
+		episode.Block,
+		chaptersToMetadata(episode.Chapters),
+		episode.ChaptersUrl,
+		episode.ChaptersType,
+		episode.Transcripts,
+		episode.Persons,
+		episode.Soundbites,
+		episode.Value,
 		puid,
 		0,
+		0,
 		util.Timestamp(),
 		0,
 	}
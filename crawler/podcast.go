@@ -1,12 +1,16 @@
 package crawler
 
 import (
+	"context"
 	"strconv"
 	"strings"
 //"fmt"
 	"github.com/kennygrant/sanitize"
 
+	"github.com/mindcastio/mindcastio/backend"
 	"github.com/mindcastio/mindcastio/backend/feed"
+	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/metrics"
 	"github.com/mindcastio/mindcastio/backend/util"
 )
 
@@ -16,25 +20,56 @@ type (
 		Subtitle    string       `json:"subtitle"`
 		Url         string       `json:"url"`
 		Feed        string       `json:"feed"`
+		NewFeedUrl  string       `json:"new_feed_url"` // itunes:new-feed-url
 		Uid         string       `json:"uid"`
 		Description string       `json:"description"`
 		Published   int64        `json:"published"`
 		Language    string       `json:"language"`
 		Image       string       `json:"image"`
 		Owner       PodcastOwner `json:"owner"`
-		Episodes    []Episode    `json:"episodes"`
+		Categories  []string     `json:"categories"`
+		Explicit    bool         `json:"explicit"`
+		Type        string       `json:"type"` // episodic | serial
+		Block       bool         `json:"block"`
+		Complete    bool         `json:"complete"`
+
+		// podcasting 2.0 namespace
+		PodcastGuid string            `json:"podcast_guid"`
+		Locked      bool              `json:"locked"`
+		LockedOwner string            `json:"locked_owner"`
+		Funding     []backend.Funding `json:"funding"`
+		Persons     []backend.Person  `json:"persons"`
+		Value       *backend.Value    `json:"value"`
+
+		Episodes []Episode `json:"episodes"`
 	}
 
 	Episode struct {
 		Title       string     `json:"title"`
 		Url         string     `json:"url"`
 		Uid         string     `json:"uid"`
+		Guid        string     `json:"guid"`
 		Description string     `json:"description"`
 		Text        string     `json:"text"`
 		Published   int64      `json:"published"`
 		Duration    int64      `json:"duration"`
 		Author      string     `json:"author"`
 		Content     MediaAsset `json:"content"`
+		Image       string     `json:"image"`
+		Explicit    bool       `json:"explicit"`
+		Season      int        `json:"season"`
+		Number      int        `json:"number"`
+		EpisodeType string     `json:"episode_type"` // full | trailer | bonus
+		Block       bool       `json:"block"`
+
+		// podcasting 2.0 namespace
+		Chapters     []Chapter            `json:"chapters"`
+		ChaptersUrl  string               `json:"chapters_url"`
+		ChaptersType string               `json:"chapters_type"`
+		Transcripts  []backend.Transcript `json:"transcripts"`
+		Persons      []backend.Person     `json:"persons"`
+		Soundbites   []backend.Soundbite  `json:"soundbites"`
+		Value        *backend.Value       `json:"value"`
 	}
 
 	PodcastOwner struct {
@@ -51,22 +86,34 @@ type (
 	Chapter struct {
 		Start string `json:"start"`
 		Title string `json:"title"`
+		Href  string `json:"href"`
+		Image string `json:"image"`
 	}
 )
 
 func ParsePodcastFeed(url string) (*Podcast, error) {
 	// parse the podcast feed
-	channel, err := feed.RSS(url)
+	channel, err := feed.RSS(url, feed.DefaultFetcher)
 	if err != nil {
 		return nil, err
 	}
 
-	return channelToPodcast(channel, url), nil
+	return channelToPodcast(channel, util.CanonicalUID(url), url), nil
 }
 
-func channelToPodcast(channel *feed.Channel, url string) *Podcast {
+func FetchPodcastFeed(ctx context.Context, idx *backend.PodcastIndex) (*Podcast, *feed.Response, error) {
+	// conditional fetch of the podcast feed
+	channel, response, err := feed.FetchContext(ctx, feed.DefaultFetcher, &feed.Request{idx.Feed, idx.ETag, idx.LastModified})
+	if err != nil || response.NotModified() {
+		return nil, response, err
+	}
 
-	uid := util.UID(url)
+	return channelToPodcast(channel, idx.Uid, idx.Feed), response, nil
+}
+
+// channelToPodcast converts the feed, the uid is passed in as it no longer
+// matches the url once the feed was migrated
+func channelToPodcast(channel *feed.Channel, uid string, url string) *Podcast {
 
 	// construct the return struct
 	owner := PodcastOwner{
@@ -91,22 +138,51 @@ func channelToPodcast(channel *feed.Channel, url string) *Podcast {
 		e := Episode{
 			item.Title,
 			item.Link,
-			util.Fingerprint(item.Title, uid),
+			EpisodeUid(&item, uid),
+			strings.TrimSpace(item.GUID),
 			sanitize.HTML(item.Description),
 			sanitize.HTML(item.Text),
 			convertDateToUnix(item.PubDate),
 			duration(item.Duration),
 			item.Author,
 			content,
+			item.Image.Href,
+			yes(item.Explicit),
+			number(item.Season),
+			number(item.Episode),
+			strings.ToLower(strings.TrimSpace(item.EpisodeType)),
+			yes(item.Block),
+			chapters(item.PscChapters),
+			item.Chapters.Url,
+			item.Chapters.Type,
+			transcripts(item.Transcript),
+			persons(item.Person),
+			soundbites(item.Soundbite),
+			value(item.Value),
 		}
 
 		episodes[i] = e
 	}
 
-	// podcast
+	// podcast, published is the date of the latest episode
 	var lastBuildDate int64 = 0
-	if len(episodes) != 0 {
-		lastBuildDate = episodes[0].Published
+	unparseable := 0
+	for i := range episodes {
+		if episodes[i].Published == 0 {
+			unparseable++
+		} else if episodes[i].Published > lastBuildDate {
+			lastBuildDate = episodes[i].Published
+		}
+	}
+
+	if unparseable > 0 {
+		logger.Warn("crawler.unparseable_date", uid, url, strconv.FormatInt((int64)(unparseable), 10))
+		metrics.Count("crawler.unparseable_date", unparseable)
+	}
+
+	image := channel.Image.URL
+	if image == "" {
+		image = channel.ItunesImage.Href
 	}
 
 	p := Podcast{
@@ -114,18 +190,48 @@ func channelToPodcast(channel *feed.Channel, url string) *Podcast {
 		channel.Subtitle,
 		channel.Link,
 		url,
+		strings.TrimSpace(channel.NewFeedUrl),
 		uid,
 		sanitize.HTML(channel.Description),
 		lastBuildDate,
 		language(channel.Language),
-		channel.Image.URL,
+		image,
 		owner,
+		categories(channel.Category),
+		yes(channel.Explicit),
+		strings.ToLower(strings.TrimSpace(channel.Type)),
+		yes(channel.Block),
+		yes(channel.Complete),
+		strings.TrimSpace(channel.PodcastGuid),
+		yes(channel.Locked.Value),
+		channel.Locked.Owner,
+		funding(channel.Funding),
+		persons(channel.Person),
+		value(channel.Value),
 		episodes,
 	}
 
 	return &p
 }
 
+// EpisodeUid returns a stable identity for an episode: the guid if there is one,
+// otherwise the enclosure url or, as a last resort, title and publishing date.
+func EpisodeUid(item *feed.Item, puid string) string {
+	guid := strings.TrimSpace(item.GUID)
+	if guid != "" {
+		return util.Fingerprint(guid, puid)
+	}
+
+	if len(item.Enclosure) > 0 {
+		enclosure := strings.TrimSpace(item.Enclosure[0].URL)
+		if enclosure != "" {
+			return util.Fingerprint(enclosure, puid)
+		}
+	}
+
+	return util.Fingerprint(item.Title+string(item.PubDate), puid)
+}
+
 func duration(d string) int64 {
 	var ss = strings.Split(d, ":")
 
@@ -144,8 +250,44 @@ func duration(d string) int64 {
 	}
 }
 
+// categories flattens the itunes categories and their sub-categories
+func categories(cc []feed.ItunesCategory) []string {
+	result := make([]string, 0)
+	for _, c := range cc {
+		text := strings.TrimSpace(c.Text)
+		if text != "" {
+			result = append(result, text)
+		}
+		result = append(result, categories(c.Category)...)
+	}
+	return result
+}
+
+// yes interprets the itunes flags, e.g. explicit, block or complete
+func yes(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "true", "explicit":
+		return true
+	default:
+		return false
+	}
+}
+
+func number(s string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// convertDateToUnix returns 0 if the date can't be parsed, the crawler then
+// falls back to the time the episode was first seen
 func convertDateToUnix(d feed.RSSDate) int64 {
-	t, _ := d.Parse()
+	t, err := d.Parse()
+	if err != nil {
+		return 0
+	}
 	return t.Unix()
 }
 
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/feed"
+)
+
+// conversion of the podcasting 2.0 and simple-chapters namespaces
+
+func chapters(cc []feed.PscChapter) []Chapter {
+	result := make([]Chapter, len(cc))
+	for i, c := range cc {
+		result[i] = Chapter{strings.TrimSpace(c.Start), strings.TrimSpace(c.Title), c.Href, c.Image}
+	}
+	return result
+}
+
+func chaptersToMetadata(cc []Chapter) []backend.Chapter {
+	result := make([]backend.Chapter, len(cc))
+	for i, c := range cc {
+		result[i] = backend.Chapter{c.Start, c.Title, c.Href, c.Image}
+	}
+	return result
+}
+
+func transcripts(tt []feed.PodcastTranscript) []backend.Transcript {
+	result := make([]backend.Transcript, len(tt))
+	for i, t := range tt {
+		result[i] = backend.Transcript{t.Url, t.Type, t.Language, t.Rel}
+	}
+	return result
+}
+
+func funding(ff []feed.PodcastFunding) []backend.Funding {
+	result := make([]backend.Funding, len(ff))
+	for i, f := range ff {
+		result[i] = backend.Funding{f.Url, strings.TrimSpace(f.Text)}
+	}
+	return result
+}
+
+func persons(pp []feed.PodcastPerson) []backend.Person {
+	result := make([]backend.Person, len(pp))
+	for i, p := range pp {
+		role := strings.ToLower(p.Role)
+		if role == "" {
+			role = "host" // default according to the spec
+		}
+		group := strings.ToLower(p.Group)
+		if group == "" {
+			group = "cast"
+		}
+		result[i] = backend.Person{strings.TrimSpace(p.Name), role, group, p.Img, p.Href}
+	}
+	return result
+}
+
+func soundbites(ss []feed.PodcastSoundbite) []backend.Soundbite {
+	result := make([]backend.Soundbite, len(ss))
+	for i, s := range ss {
+		result[i] = backend.Soundbite{s.StartTime, s.Duration, strings.TrimSpace(s.Title)}
+	}
+	return result
+}
+
+func value(v *feed.PodcastValue) *backend.Value {
+	if v == nil {
+		return nil
+	}
+
+	recipients := make([]backend.ValueRecipient, len(v.Recipients))
+	for i, r := range v.Recipients {
+		split, _ := strconv.Atoi(r.Split)
+		recipients[i] = backend.ValueRecipient{r.Name, r.Type, r.Address, split, yes(r.Fee), r.CustomKey, r.CustomValue}
+	}
+
+	return &backend.Value{v.Type, v.Method, v.Suggested, recipients}
+}
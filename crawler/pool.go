@@ -0,0 +1,165 @@
+package crawler
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/feed"
+)
+
+type (
+	// crawlQueue hands out feeds to the workers, respecting the per-host limit
+	crawlQueue struct {
+		sync.Mutex
+		cond *sync.Cond
+
+		pending   []backend.PodcastIndex
+		hosts     map[string]int
+		hostLimit int
+		closed    bool
+
+		crawled  int
+		failed   int
+		deferred int
+		aborted  int
+	}
+
+	crawlStats struct {
+		Crawled  int
+		Failed   int
+		Skipped  int // not started before the deadline, or cancelled on shutdown
+		Deferred int // skipped for politeness
+	}
+)
+
+// crawlAll crawls the batch with a bounded number of workers, suspended feeds are only probed.
+// At the deadline crawls in progress are aborted, so that a run never overlaps the next one, and back off like
+// failed ones. On shutdown they are cancelled before their writes, within the grace period. Feeds not started
+// or cancelled are skipped, they are still expired and come up again with the next run.
+// The batch was leased by IndexClaim, every feed is released when done or skipped.
+func crawlAll(ctx context.Context, batch []backend.PodcastIndex, workers int, hostWorkers int, deadline time.Duration) *crawlStats {
+
+	q := crawlQueue{
+		pending:   batch,
+		hosts:     make(map[string]int),
+		hostLimit: hostWorkers,
+	}
+	q.cond = sync.NewCond(&q)
+
+	// every crawl ends with the run, and a run never outlasts the leases, see CRAWL_TIMEOUT
+	timeout := time.Second * time.Duration(backend.CRAWL_TIMEOUT)
+	if deadline > timeout {
+		deadline = timeout
+	}
+
+	run_ctx, cancel := context.WithTimeout(ctx, deadline)
+	defer cancel()
+
+	// stop handing out work once the deadline is reached or on shutdown
+	go func() {
+		<-run_ctx.Done()
+		q.close()
+	}()
+
+	if workers > len(batch) {
+		workers = len(batch)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				idx, host, ok := q.next()
+				if !ok {
+					return
+				}
+
+				var err error
+				if idx.Errors > backend.MAX_ERRORS {
+					err = ProbePodcastFeed(run_ctx, idx.Uid)
+				} else {
+					err = CrawlPodcastFeed(run_ctx, idx.Uid)
+				}
+				q.done(host, err)
+
+				crawlRelease(idx)
+			}
+		}()
+	}
+	wg.Wait()
+
+	q.Lock()
+	defer q.Unlock()
+
+	// not started before the deadline, leave them to the next run of any instance
+	for i := range q.pending {
+		crawlRelease(&q.pending[i])
+	}
+
+	return &crawlStats{q.crawled, q.failed, len(q.pending) + q.aborted, q.deferred}
+}
+
+func (q *crawlQueue) next() (*backend.PodcastIndex, string, bool) {
+	q.Lock()
+	defer q.Unlock()
+
+	for {
+		if q.closed || len(q.pending) == 0 {
+			return nil, "", false
+		}
+
+		for i := range q.pending {
+			host := feedHost(q.pending[i].Feed)
+			if q.hosts[host] < q.hostLimit {
+				idx := q.pending[i]
+				q.pending = append(q.pending[:i], q.pending[i+1:]...)
+				q.hosts[host]++
+
+				return &idx, host, true
+			}
+		}
+
+		// all remaining feeds are on busy hosts, wait for a worker to finish
+		q.cond.Wait()
+	}
+}
+
+func (q *crawlQueue) done(host string, err error) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.hosts[host]--
+	if err == context.Canceled {
+		q.aborted++
+	} else if feed.IsDeferred(err) {
+		q.deferred++
+	} else if err != nil {
+		q.failed++
+	} else {
+		q.crawled++
+	}
+
+	q.cond.Broadcast()
+}
+
+func (q *crawlQueue) close() {
+	q.Lock()
+	defer q.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+func feedHost(feed string) string {
+	u, err := url.Parse(feed)
+	if err != nil || u.Host == "" {
+		return feed
+	}
+	return strings.ToLower(u.Host)
+}
@@ -0,0 +1,130 @@
+package crawler
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/nats-io/nats"
+
+	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/feed"
+	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/messaging"
+	"github.com/mindcastio/mindcastio/backend/metrics"
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+// ScheduleSubmitJobs works through the queued submissions: the feed is checked,
+// added to the index and crawled right away. It stops between jobs once ctx is cancelled.
+func ScheduleSubmitJobs(ctx context.Context) {
+
+	count := 0
+	for ; count < backend.SUBMIT_JOB_BATCH; count++ {
+		if ctx.Err() != nil {
+			break
+		}
+		job := backend.SubmitJobNext()
+		if job == nil {
+			break
+		}
+		if !processSubmitJob(ctx, job) {
+			break // the host is busy, the job comes up again with the next run
+		}
+	}
+
+	if count > 0 {
+		logger.Log("crawler.schedule_submit_jobs.done", strconv.FormatInt((int64)(count), 10))
+		metrics.Count("crawler.submit_jobs", count)
+	}
+}
+
+// SubscribeSubmitJobs processes submissions as soon as the API announces them. Only one
+// crawler of the queue group gets each one, ScheduleSubmitJobs picks up any that were missed.
+// Jobs in progress are tracked, once the tracker is closing new ones are left for the sweep.
+func SubscribeSubmitJobs(ctx context.Context, t *util.Tracker) (*nats.Subscription, error) {
+	return messaging.QueueSubscribe(messaging.SUBMIT_SUBJECT, messaging.CRAWLER_QUEUE, func(msg *nats.Msg) {
+		if !t.Add() {
+			return
+		}
+		defer t.Done()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		e, err := messaging.Decode(msg)
+		if err != nil {
+			logger.Error("crawler.submit_job.error", err, string(msg.Data))
+			return
+		}
+
+		job := backend.SubmitJobClaim(e.Id)
+		if job == nil {
+			return // already taken care of
+		}
+		processSubmitJob(ctx, job)
+		metrics.Count("crawler.submit_jobs.messaged", 1)
+	})
+}
+
+// processSubmitJob is false if the job was put back into the queue because it was deferred,
+// or cancelled on shutdown. A crawled job is moved on to indexed by the indexer, once the podcast is searchable.
+func processSubmitJob(ctx context.Context, job *backend.SubmitJob) bool {
+
+	logger.Log("process_submit_job", job.Id, job.Feed)
+
+	// a job put back into the queue was accepted already, it only needs the crawl
+	uid := job.Uid
+	if uid == "" {
+		submission, err := backend.SubmitPodcastFeed(job.Feed)
+		if err == feed.ErrDeferred {
+			submitJobDone(job, backend.JOB_QUEUED, err.Error(), "")
+			return false
+		}
+		if err != nil {
+			submitJobDone(job, backend.JOB_FAILED, err.Error(), "")
+			return true
+		}
+
+		switch submission.Status {
+		case backend.SUBMISSION_REJECTED:
+			submitJobDone(job, backend.JOB_FAILED, submission.Reason, "")
+			return true
+		case backend.SUBMISSION_DUPLICATE:
+			state := backend.SubmitJobState(submission.Uid)
+			if state != backend.JOB_QUEUED {
+				submitJobDone(job, state, submission.Status, submission.Uid)
+				return true
+			}
+			// known but never crawled, the crawl is still to come
+		}
+
+		// remember the uid already, the crawl might take a while
+		uid = submission.Uid
+		backend.SubmitJobUpdate(job.Id, backend.JOB_CRAWLING, "", uid)
+	}
+
+	err := CrawlLeased(ctx, uid)
+	if feed.IsDeferred(err) || err == ErrPodcastLeased || err == context.Canceled {
+		// not crawled yet, try again with the next run
+		submitJobDone(job, backend.JOB_QUEUED, err.Error(), uid)
+		return false
+	}
+	if err != nil {
+		submitJobDone(job, backend.JOB_FAILED, err.Error(), uid)
+	} else {
+		submitJobDone(job, backend.JOB_CRAWLED, "", uid)
+	}
+
+	return true
+}
+
+func submitJobDone(job *backend.SubmitJob, status string, reason string, uid string) {
+	err := backend.SubmitJobUpdate(job.Id, status, reason, uid)
+	if err != nil {
+		logger.Error("process_submit_job.error", err, job.Id)
+	}
+
+	logger.Log("process_submit_job.done", job.Id, status, uid, reason)
+	metrics.Count("crawler.submit_jobs."+status, 1)
+}
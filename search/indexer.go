@@ -1,15 +1,20 @@
 package search
 
 import (
+	"context"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/nats-io/nats"
+
 	"github.com/mindcastio/mindcastio/backend"
 	"github.com/mindcastio/mindcastio/backend/datastore"
 	"github.com/mindcastio/mindcastio/backend/environment"
 	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/messaging"
 	"github.com/mindcastio/mindcastio/backend/metrics"
 	"github.com/mindcastio/mindcastio/backend/util"
 )
@@ -26,7 +31,9 @@ type (
 	}
 )
 
-func SchedulePodcastIndexing() {
+// SchedulePodcastIndexing sends a batch of new or changed podcasts to the search index,
+// once ctx is cancelled the rest of the batch is left for the next run
+func SchedulePodcastIndexing(ctx context.Context) {
 
 	start := time.Now()
 	logger.Log("schedule_podcast_indexing")
@@ -38,37 +45,22 @@ func SchedulePodcastIndexing() {
 	logger.Log("schedule_podcast_indexing.scheduling", strconv.FormatInt((int64)(count), 10))
 
 	if count > 0 {
-		ds := datastore.GetDataStore()
-		defer ds.Close()
-
-		podcast_metadata := ds.Collection(datastore.PODCASTS_COL)
-
-		for i := 0; i < count; i++ {
-			err := podcastAddToSearchIndex(&notIndexed[i])
-			if err != nil {
-				logger.Error("schedule_podcast_indexing.error.1", err, notIndexed[i].Uid)
-				metrics.Error("schedule_podcast_indexing.error.1", err.Error(), []string{notIndexed[i].Uid})
-				// abort or disable at some point?
-			}
+		count = podcastsIndex(ctx, notIndexed)
+		metrics.Count("indexer.podcasts.count", count)
+	}
 
-			// update the metadata
-			notIndexed[i].Version = backend.SEARCH_REVISION
-			notIndexed[i].Updated = util.Timestamp()
-			err = podcast_metadata.Update(bson.M{"uid": notIndexed[i].Uid}, &notIndexed[i])
-			if err != nil {
-				logger.Error("schedule_podcast_indexing.error.2", err, notIndexed[i].Uid)
-				metrics.Error("schedule_podcast_indexing.error.2", err.Error(), []string{notIndexed[i].Uid})
-				// abort or disable at some point?
-			}
+	// submissions of podcasts that were searchable already, or were merged into another one
+	for _, uid := range backend.SubmitJobCrawledUids() {
+		if backend.SubmitJobState(uid) == backend.JOB_INDEXED {
+			submitJobIndexed(uid)
 		}
-		metrics.Count("indexer.podcasts.count", count)
 	}
 
 	logger.Log("schedule_podcast_indexing.done")
 	metrics.Histogram("indexer.podcasts.duration", (float64)(util.ElapsedTimeSince(start)))
 }
 
-func ScheduleEpisodeIndexing() {
+func ScheduleEpisodeIndexing(ctx context.Context) {
 
 	start := time.Now()
 	logger.Log("schedule_episode_indexing")
@@ -80,34 +72,149 @@ func ScheduleEpisodeIndexing() {
 	logger.Log("schedule_episode_indexing.scheduling", strconv.FormatInt((int64)(count), 10))
 
 	if count > 0 {
-		ds := datastore.GetDataStore()
-		defer ds.Close()
+		count = episodesIndex(ctx, notIndexed)
+		metrics.Count("indexer.episodes.count", count)
+	}
 
-		episodes_metadata := ds.Collection(datastore.EPISODES_COL)
+	logger.Log("schedule_episode_indexing.done")
+	metrics.Histogram("indexer.episodes.duration", (float64)(util.ElapsedTimeSince(start)))
+}
 
-		for i := 0; i < count; i++ {
-			err := episodeAddToSearchIndex(&notIndexed[i])
-			if err != nil {
-				logger.Error("schedule_episode_indexing.error.1", err, notIndexed[i].Uid)
-				metrics.Error("schedule_episode_indexing.error.1", err.Error(), []string{notIndexed[i].Uid})
-				// abort or disable at some point?
-			}
+// SubscribeIndexRequests indexes a podcast and its episodes as soon as the crawler announces
+// a change. Only one indexer of the queue group gets each request, the sweeps catch up on missed ones.
+// Requests in progress are tracked, once the tracker is closing new ones are left for the sweeps.
+func SubscribeIndexRequests(ctx context.Context, t *util.Tracker) (*nats.Subscription, error) {
+	return messaging.QueueSubscribe(messaging.INDEX_SUBJECT, messaging.INDEXER_QUEUE, func(msg *nats.Msg) {
+		if !t.Add() {
+			return
+		}
+		defer t.Done()
 
-			// update the metadata
-			notIndexed[i].Version = backend.SEARCH_REVISION
-			notIndexed[i].Updated = util.Timestamp()
-			err = episodes_metadata.Update(bson.M{"uid": notIndexed[i].Uid}, &notIndexed[i])
-			if err != nil {
-				logger.Error("schedule_episode_indexing.error.2", err, notIndexed[i].Uid)
-				metrics.Error("schedule_episode_indexing.error.2", err.Error(), []string{notIndexed[i].Uid})
-				// abort or disable at some point?
-			}
+		if ctx.Err() != nil {
+			return
 		}
+
+		e, err := messaging.Decode(msg)
+		if err != nil {
+			logger.Error("indexer.index_request.error", err, string(msg.Data))
+			return
+		}
+		IndexPodcast(ctx, e.Id)
+	})
+}
+
+// IndexPodcast brings a podcast and its episodes in the search index up to date
+func IndexPodcast(ctx context.Context, uid string) {
+
+	start := time.Now()
+	logger.Log("index_podcast", uid)
+
+	count := 0
+	podcasts := podcastSearchNotIndexedByUid(uid, backend.SEARCH_REVISION)
+	if len(podcasts) > 0 {
+		metrics.Count("indexer.podcasts.count", podcastsIndex(ctx, podcasts))
+	}
+
+	episodes := episodesSearchNotIndexedByPodcast(uid, backend.SEARCH_REVISION)
+	if len(episodes) > 0 {
+		count = episodesIndex(ctx, episodes)
 		metrics.Count("indexer.episodes.count", count)
 	}
 
-	logger.Log("schedule_episode_indexing.done")
-	metrics.Histogram("indexer.episodes.duration", (float64)(util.ElapsedTimeSince(start)))
+	logger.Log("index_podcast.done", uid, strconv.FormatInt((int64)(count), 10))
+	metrics.Histogram("indexer.messaged.duration", (float64)(util.ElapsedTimeSince(start)))
+}
+
+// podcastsIndex returns how many podcasts it indexed before ctx was cancelled
+func podcastsIndex(ctx context.Context, notIndexed []backend.PodcastMetadata) int {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	podcast_metadata := ds.Collection(datastore.PODCASTS_COL)
+
+	for i := range notIndexed {
+		if ctx.Err() != nil {
+			return i
+		}
+
+		var err error
+		if notIndexed[i].MergedInto != "" {
+			// a duplicate, only the podcast it was merged into stays searchable
+			err = podcastRemoveFromSearchIndex(notIndexed[i].Uid)
+		} else {
+			err = podcastAddToSearchIndex(&notIndexed[i])
+			if err == nil {
+				submitJobIndexed(notIndexed[i].Uid)
+			}
+		}
+		if err != nil {
+			logger.Error("schedule_podcast_indexing.error.1", err, notIndexed[i].Uid)
+			metrics.Error("schedule_podcast_indexing.error.1", err.Error(), []string{notIndexed[i].Uid})
+			// abort or disable at some point?
+		}
+
+		// mark it as indexed, unless it changed since it was read and needs indexing again
+		err = podcast_metadata.Update(indexedSelector(notIndexed[i].Uid, notIndexed[i].Updated, notIndexed[i].Version), bson.M{"$set": bson.M{"version": backend.SEARCH_REVISION}})
+		if err != nil && err != mgo.ErrNotFound {
+			logger.Error("schedule_podcast_indexing.error.2", err, notIndexed[i].Uid)
+			metrics.Error("schedule_podcast_indexing.error.2", err.Error(), []string{notIndexed[i].Uid})
+			// abort or disable at some point?
+		}
+	}
+
+	return len(notIndexed)
+}
+
+// episodesIndex returns how many episodes it indexed before ctx was cancelled
+func episodesIndex(ctx context.Context, notIndexed []backend.EpisodeMetadata) int {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	episodes_metadata := ds.Collection(datastore.EPISODES_COL)
+
+	for i := range notIndexed {
+		if ctx.Err() != nil {
+			return i
+		}
+
+		var err error
+		if notIndexed[i].Removed != 0 {
+			// vanished from the feed
+			err = episodeRemoveFromSearchIndex(notIndexed[i].PodcastUid, notIndexed[i].Uid)
+		} else {
+			err = episodeAddToSearchIndex(&notIndexed[i])
+		}
+		if err != nil {
+			logger.Error("schedule_episode_indexing.error.1", err, notIndexed[i].Uid)
+			metrics.Error("schedule_episode_indexing.error.1", err.Error(), []string{notIndexed[i].Uid})
+			// abort or disable at some point?
+		}
+
+		// mark it as indexed, unless it changed since it was read and needs indexing again
+		err = episodes_metadata.Update(indexedSelector(notIndexed[i].Uid, notIndexed[i].Updated, notIndexed[i].Version), bson.M{"$set": bson.M{"version": backend.SEARCH_REVISION}})
+		if err != nil && err != mgo.ErrNotFound {
+			logger.Error("schedule_episode_indexing.error.2", err, notIndexed[i].Uid)
+			metrics.Error("schedule_episode_indexing.error.2", err.Error(), []string{notIndexed[i].Uid})
+			// abort or disable at some point?
+		}
+	}
+
+	return len(notIndexed)
+}
+
+// submitJobIndexed tells the submitters of a podcast that it is searchable now
+func submitJobIndexed(uid string) {
+	err := backend.SubmitJobIndexed(uid)
+	if err != nil {
+		logger.Error("indexer.submit_job.error", err, uid)
+	}
+}
+
+// indexedSelector matches a podcast or episode only if it is unchanged since the snapshot was read
+func indexedSelector(uid string, updated int64, version int) bson.M {
+	return bson.M{"uid": uid, "updated": updated, "version": version}
 }
 
 func podcastAddToSearchIndex(podcast *backend.PodcastMetadata) error {
@@ -128,6 +235,13 @@ func podcastAddToSearchIndex(podcast *backend.PodcastMetadata) error {
 
 }
 
+func podcastRemoveFromSearchIndex(uid string) error {
+
+	uri := strings.Join([]string{environment.GetEnvironment().SearchServiceUrl(), "/podcasts/podcast/", uid}, "")
+
+	return util.Delete(uri)
+}
+
 func episodeAddToSearchIndex(episode *backend.EpisodeMetadata) error {
 
 	podcast := backend.PodcastLookup(episode.PodcastUid)
@@ -150,6 +264,24 @@ func episodeAddToSearchIndex(episode *backend.EpisodeMetadata) error {
 
 }
 
+// EpisodeRekeySearchIndex moves an already indexed episode to its new uid
+func EpisodeRekeySearchIndex(oldUid string, episode *backend.EpisodeMetadata) error {
+	err := episodeAddToSearchIndex(episode)
+	if err != nil {
+		return err
+	}
+
+	return episodeRemoveFromSearchIndex(episode.PodcastUid, oldUid)
+}
+
+func episodeRemoveFromSearchIndex(puid string, uid string) error {
+
+	id := strings.Join([]string{puid, uid}, "-")
+	uri := strings.Join([]string{environment.GetEnvironment().SearchServiceUrl(), "/podcasts/episode/", id}, "")
+
+	return util.Delete(uri)
+}
+
 func podcastSearchNotIndexed(limit int, version int) []backend.PodcastMetadata {
 
 	ds := datastore.GetDataStore()
@@ -191,3 +323,29 @@ func episodesSearchNotIndexed(limit int, version int) []backend.EpisodeMetadata
 
 	return results
 }
+
+func podcastSearchNotIndexedByUid(uid string, version int) []backend.PodcastMetadata {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	podcast_metadata := ds.Collection(datastore.PODCASTS_COL)
+
+	results := []backend.PodcastMetadata{}
+	podcast_metadata.Find(bson.M{"uid": uid, "version": bson.M{"$lt": version}}).All(&results)
+
+	return results
+}
+
+func episodesSearchNotIndexedByPodcast(uid string, version int) []backend.EpisodeMetadata {
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	episodes_metadata := ds.Collection(datastore.EPISODES_COL)
+
+	results := []backend.EpisodeMetadata{}
+	episodes_metadata.Find(bson.M{"podcastuid": uid, "version": bson.M{"$lt": version}}).All(&results)
+
+	return results
+}
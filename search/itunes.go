@@ -3,6 +3,8 @@ package search
 import (
 	"strings"
 
+	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/logger"
 	"github.com/mindcastio/mindcastio/backend/util"
 )
 
@@ -64,17 +66,31 @@ func SearchITunes(q string) ([]*Result, error) {
 	} else {
 		podcasts := make([]*Result, len(response.Items))
 
+		// podcasts in the index are answered with their uid, the others with the one they would get
+		feeds := make([]string, len(response.Items))
+		for i := range response.Items {
+			feeds[i] = response.Items[i].FeedUrl
+		}
+		uids, err := backend.IndexResolveUids(feeds)
+		if err != nil {
+			logger.Error("search.itunes.error", err)
+		}
+
 		for i, item := range response.Items {
-			podcasts[i] = iTunesToResult(&item)
+			podcasts[i] = iTunesToResult(&item, uids[item.FeedUrl])
 		}
 
 		return podcasts, nil
 	}
 }
 
-func iTunesToResult(item *iTunesItem) *Result {
+func iTunesToResult(item *iTunesItem, uid string) *Result {
+	if uid == "" {
+		uid = util.CanonicalUID(item.FeedUrl)
+	}
+
 	result := Result{
-		util.UID(item.FeedUrl),
+		uid,
 		"podcast",
 		item.CollectionName,
 		"",
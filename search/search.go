@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/logger"
 	"github.com/mindcastio/mindcastio/backend/metrics"
 	"github.com/mindcastio/mindcastio/backend/util"
 )
@@ -41,7 +42,12 @@ func Search(q string, page int, limit int) *SearchResult {
 			for i := range result2 {
 				feeds[i] = result2[i].Feed
 			}
-			backend.BulkSubmitPodcastFeed(feeds)
+			outcomes, err := backend.BulkSubmitPodcastFeed(feeds)
+			if err != nil {
+				logger.Error("search.external.error", err, q)
+			} else {
+				logger.Log("search.external.submit", q, backend.BulkSummary(outcomes))
+			}
 
 			metrics.Count("search.external.count", len(result2))
 		}()
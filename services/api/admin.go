@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"github.com/mindcastio/mindcastio/crawler"
+
+	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/environment"
+	"github.com/mindcastio/mindcastio/backend/feed"
+	"github.com/mindcastio/mindcastio/backend/metrics"
+
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+const (
+	ADMIN_PAGE_SIZE     int   = 25
+	ADMIN_MAX_PAGE_SIZE int   = 100
+	ADMIN_MAX_IMPORT    int64 = 10 * 1024 * 1024 // bytes
+)
+
+var (
+	ErrImportTooLarge = errors.New("import too large")
+)
+
+// adminAuthentication protects everything below ADMIN_ENDPOINT with basic auth,
+// without an ADMIN_PASSWORD nobody gets in
+func adminAuthentication(env *environment.Environment) rest.Middleware {
+	return &rest.IfMiddleware{
+		Condition: func(r *rest.Request) bool {
+			return strings.HasPrefix(r.URL.Path, ADMIN_ENDPOINT)
+		},
+		IfTrue: &rest.AuthBasicMiddleware{
+			Realm: "mindcastio admin",
+			Authenticator: func(user string, password string) bool {
+				if env.AdminPassword() == "" {
+					return false
+				}
+				return subtle.ConstantTimeCompare([]byte(user), []byte(env.AdminUser())) == 1 &&
+					subtle.ConstantTimeCompare([]byte(password), []byte(env.AdminPassword())) == 1
+			},
+		},
+	}
+}
+
+func admin_history_endpoint(w rest.ResponseWriter, r *rest.Request) {
+	start := time.Now()
+
+	uid := strings.Trim(r.PathParam("id"), " ")
+	if uid == "" {
+		backend.JsonApiErrorResponse(w, "api.admin.history.error", "missing parameter", nil)
+		metrics.Error("api.admin.history.error", "", nil)
+		return
+	}
+
+	page, size := pageParams(r)
+
+	result, err := backend.CrawlHistoryLookup(uid, page, size)
+	if err != nil {
+		backend.JsonApiErrorResponse(w, "api.admin.history.error", "", err)
+		metrics.Error("api.admin.history.error", err.Error(), []string{uid})
+		return
+	}
+	backend.Response(w, result)
+
+	// metrics
+	metrics.Count("api.total.count", 1)
+	metrics.Count("api.admin.history.count", 1)
+	metrics.Histogram("api.admin.history.duration", (float64)(util.ElapsedTimeSince(start)))
+}
+
+// admin_feeds_endpoint lists feeds with crawl errors, &suspended=true or &dead=true only those
+func admin_feeds_endpoint(w rest.ResponseWriter, r *rest.Request) {
+	start := time.Now()
+
+	page, size := pageParams(r)
+	suspended := r.URL.Query().Get("suspended") == "true"
+	dead := r.URL.Query().Get("dead") == "true"
+
+	result, err := backend.IndexLookupFailing(suspended, dead, page, size)
+	if err != nil {
+		backend.JsonApiErrorResponse(w, "api.admin.feeds.error", "", err)
+		metrics.Error("api.admin.feeds.error", err.Error(), nil)
+		return
+	}
+	backend.Response(w, result)
+
+	// metrics
+	metrics.Count("api.total.count", 1)
+	metrics.Count("api.admin.feeds.count", 1)
+	metrics.Histogram("api.admin.feeds.duration", (float64)(util.ElapsedTimeSince(start)))
+}
+
+func admin_resume_endpoint(w rest.ResponseWriter, r *rest.Request) {
+	start := time.Now()
+
+	idx := adminLookupIndex(w, r, "api.admin.resume.error")
+	if idx == nil {
+		return
+	}
+
+	err := backend.IndexResume(idx.Uid)
+	if err != nil {
+		backend.JsonApiErrorResponse(w, "api.admin.resume.error", "", err)
+		metrics.Error("api.admin.resume.error", err.Error(), []string{idx.Uid})
+		return
+	}
+
+	backend.AdminAuditLog(adminUser(r), "resume", idx.Uid, "errors="+strconv.Itoa(idx.Errors))
+	backend.StatusResponse(w, http.StatusOK)
+
+	// metrics
+	metrics.Count("api.total.count", 1)
+	metrics.Count("api.admin.resume.count", 1)
+	metrics.Histogram("api.admin.resume.duration", (float64)(util.ElapsedTimeSince(start)))
+}
+
+// admin_crawl_endpoint crawls a feed right away and returns the outcome from the crawl history.
+// The crawl holds the lease on the podcast, if a crawler has it already the answer is 409.
+func admin_crawl_endpoint(w rest.ResponseWriter, r *rest.Request) {
+	start := time.Now()
+
+	idx := adminLookupIndex(w, r, "api.admin.crawl.error")
+	if idx == nil {
+		return
+	}
+
+	err := crawler.CrawlLeased(context.Background(), idx.Uid)
+	if err == crawler.ErrPodcastLeased {
+		backend.JsonApiErrorStatusResponse(w, http.StatusConflict, "api.admin.crawl.error", "", err)
+		return
+	}
+	if err == crawler.ErrPodcastNotFound {
+		backend.JsonApiErrorStatusResponse(w, http.StatusNotFound, "api.admin.crawl.error", "", err)
+		return
+	}
+
+	detail := "ok"
+	if err != nil {
+		detail = err.Error()
+	}
+	backend.AdminAuditLog(adminUser(r), "crawl", idx.Uid, detail)
+
+	result, err := backend.CrawlHistoryLookup(idx.Uid, 1, 1)
+	if err != nil {
+		backend.JsonApiErrorResponse(w, "api.admin.crawl.error", "", err)
+		metrics.Error("api.admin.crawl.error", err.Error(), []string{idx.Uid})
+		return
+	}
+	backend.Response(w, result)
+
+	// metrics
+	metrics.Count("api.total.count", 1)
+	metrics.Count("api.admin.crawl.count", 1)
+	metrics.Histogram("api.admin.crawl.duration", (float64)(util.ElapsedTimeSince(start)))
+}
+
+type rateType struct {
+	Rate int // min., 0 returns to adaptive scheduling
+}
+
+func admin_rate_endpoint(w rest.ResponseWriter, r *rest.Request) {
+	start := time.Now()
+
+	idx := adminLookupIndex(w, r, "api.admin.rate.error")
+	if idx == nil {
+		return
+	}
+
+	rt := rateType{}
+	err := r.DecodeJsonPayload(&rt)
+	if err != nil {
+		backend.JsonApiErrorResponse(w, "api.admin.rate.error", "missing parameter", err)
+		metrics.Error("api.admin.rate.error", err.Error(), []string{idx.Uid})
+		return
+	}
+
+	err = backend.IndexSetRate(idx.Uid, rt.Rate)
+	if err != nil {
+		backend.JsonApiErrorResponse(w, "api.admin.rate.error", "", err)
+		metrics.Error("api.admin.rate.error", err.Error(), []string{idx.Uid})
+		return
+	}
+
+	backend.AdminAuditLog(adminUser(r), "rate", idx.Uid, strconv.Itoa(idx.UpdateRate)+"->"+strconv.Itoa(rt.Rate))
+	backend.StatusResponse(w, http.StatusOK)
+
+	// metrics
+	metrics.Count("api.total.count", 1)
+	metrics.Count("api.admin.rate.count", 1)
+	metrics.Histogram("api.admin.rate.duration", (float64)(util.ElapsedTimeSince(start)))
+}
+
+type importReport struct {
+	Total     int                   `json:"total"`
+	Added     int                   `json:"added"`
+	Duplicate int                   `json:"duplicate"`
+	Invalid   int                   `json:"invalid"`
+	Error     int                   `json:"error"`
+	Results   []backend.BulkOutcome `json:"results"`
+}
+
+// admin_import_endpoint adds the feeds of an OPML document or a newline-delimited list
+// of urls to the index and reports the outcome per url
+func admin_import_endpoint(w rest.ResponseWriter, r *rest.Request) {
+	start := time.Now()
+
+	urls, err := importUrls(r.Body)
+	if err != nil {
+		backend.JsonApiErrorResponse(w, "api.admin.import.error", "", err)
+		metrics.Error("api.admin.import.error", err.Error(), nil)
+		return
+	}
+	if len(urls) == 0 {
+		backend.JsonApiErrorResponse(w, "api.admin.import.error", "missing parameter", nil)
+		metrics.Error("api.admin.import.error", "missing parameter", nil)
+		return
+	}
+
+	outcomes, err := backend.BulkSubmitPodcastFeed(urls)
+	if err != nil {
+		backend.JsonApiErrorResponse(w, "api.admin.import.error", "", err)
+		metrics.Error("api.admin.import.error", err.Error(), nil)
+		return
+	}
+
+	report := importReport{
+		len(outcomes),
+		backend.BulkCount(outcomes, backend.BULK_ADDED),
+		backend.BulkCount(outcomes, backend.BULK_DUPLICATE),
+		backend.BulkCount(outcomes, backend.BULK_INVALID),
+		backend.BulkCount(outcomes, backend.BULK_ERROR),
+		outcomes,
+	}
+
+	backend.AdminAuditLog(adminUser(r), "import", "", "total="+strconv.Itoa(report.Total)+" added="+strconv.Itoa(report.Added))
+	backend.Response(w, &report)
+
+	// metrics
+	metrics.Count("api.total.count", 1)
+	metrics.Count("api.admin.import.count", 1)
+	metrics.Count("api.admin.import.feeds", report.Added)
+	metrics.Histogram("api.admin.import.duration", (float64)(util.ElapsedTimeSince(start)))
+}
+
+// admin_export_endpoint returns the index as OPML, &language=en or &tag=comedy export a subset
+func admin_export_endpoint(w rest.ResponseWriter, r *rest.Request) {
+	start := time.Now()
+
+	language := strings.TrimSpace(r.URL.Query().Get("language"))
+	tag := strings.TrimSpace(r.URL.Query().Get("tag"))
+
+	outlines, err := backend.IndexExport(language, tag)
+	if err != nil {
+		backend.JsonApiErrorResponse(w, "api.admin.export.error", "", err)
+		metrics.Error("api.admin.export.error", err.Error(), nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"mindcastio.opml\"")
+	w.WriteHeader(http.StatusOK)
+
+	err = feed.WriteOPML(w.(http.ResponseWriter), "mindcast.io podcasts", outlines)
+	if err != nil {
+		metrics.Error("api.admin.export.error", err.Error(), nil)
+	}
+
+	// metrics
+	metrics.Count("api.total.count", 1)
+	metrics.Count("api.admin.export.count", 1)
+	metrics.Histogram("api.admin.export.duration", (float64)(util.ElapsedTimeSince(start)))
+}
+
+// importUrls reads an OPML document, or one url per line if the body is not XML.
+// Empty lines and lines starting with # are skipped.
+func importUrls(r io.Reader) ([]string, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(r, ADMIN_MAX_IMPORT+1))
+	if err != nil {
+		return nil, err
+	}
+	if (int64)(len(body)) > ADMIN_MAX_IMPORT {
+		return nil, ErrImportTooLarge
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("<")) {
+		return feed.ParseOPML(bytes.NewReader(body))
+	}
+
+	urls := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+
+	return urls, scanner.Err()
+}
+
+// adminLookupIndex writes the error response itself if the podcast is unknown
+func adminLookupIndex(w rest.ResponseWriter, r *rest.Request, code string) *backend.PodcastIndex {
+	uid := strings.Trim(r.PathParam("id"), " ")
+	if uid == "" {
+		backend.JsonApiErrorResponse(w, code, "missing parameter", nil)
+		metrics.Error(code, "", nil)
+		return nil
+	}
+
+	idx := backend.IndexLookup(uid)
+	if idx == nil {
+		backend.JsonApiErrorStatusResponse(w, http.StatusNotFound, code, "podcast not found", nil)
+		metrics.Error(code, "podcast not found", []string{uid})
+		return nil
+	}
+
+	return idx
+}
+
+// adminUser is set by the basic auth middleware
+func adminUser(r *rest.Request) string {
+	user, _ := r.Env["REMOTE_USER"].(string)
+	return user
+}
+
+// pageParams returns &page=1&size=25
+func pageParams(r *rest.Request) (int, int) {
+	var size int = ADMIN_PAGE_SIZE
+	var page int = 1
+
+	if len(r.URL.Query()["size"]) != 0 {
+		ss, _ := strconv.ParseInt(r.URL.Query()["size"][0], 10, 64)
+		size = (int)(ss)
+		if size < 1 {
+			size = ADMIN_PAGE_SIZE
+		}
+		if size > ADMIN_MAX_PAGE_SIZE {
+			size = ADMIN_MAX_PAGE_SIZE
+		}
+	}
+
+	if len(r.URL.Query()["page"]) != 0 {
+		pp, _ := strconv.ParseInt(r.URL.Query()["page"][0], 10, 64)
+		page = (int)(pp)
+		if page < 1 {
+			page = 1
+		}
+	}
+
+	return page, size
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ant0ine/go-json-rest/rest"
+
+	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/artwork"
+	"github.com/mindcastio/mindcastio/backend/metrics"
+
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+const (
+	ARTWORK_MAX_AGE int = 86400 // sec, thumbnails change rarely
+)
+
+// artwork_endpoint serves a thumbnail of the podcast artwork, see artwork.Sizes. Conditional
+// requests are answered with 304 if the thumbnail did not change.
+func artwork_endpoint(w rest.ResponseWriter, r *rest.Request) {
+	start := time.Now()
+
+	uid := strings.Trim(r.PathParam("id"), " ")
+	size := strings.Trim(r.PathParam("size"), " ")
+	if uid == "" || !artwork.IsSize(size) {
+		backend.JsonApiErrorResponse(w, "api.artwork.error", "invalid parameter", nil)
+		metrics.Error("api.artwork.error", "invalid parameter", []string{uid, size})
+		return
+	}
+
+	result := backend.PodcastLookup(uid)
+	if result != nil && result.MergedInto != "" {
+		uid = result.MergedInto
+		result = backend.PodcastLookup(uid)
+	}
+	if result == nil || result.Artwork == "" {
+		backend.JsonApiErrorStatusResponse(w, http.StatusNotFound, "api.artwork.error", "artwork not found", nil)
+		metrics.Error("api.artwork.error", "artwork not found", []string{uid})
+		return
+	}
+
+	data, modified, err := artwork.GetStore().Get(artwork.Key(uid, size))
+	if err == artwork.ErrBlobNotFound {
+		backend.JsonApiErrorStatusResponse(w, http.StatusNotFound, "api.artwork.error", "artwork not found", err)
+		metrics.Error("api.artwork.error", err.Error(), []string{uid})
+		return
+	}
+	if err != nil {
+		backend.JsonApiErrorResponse(w, "api.artwork.error", "", err)
+		metrics.Error("api.artwork.error", err.Error(), []string{uid})
+		return
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(data))
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", ARTWORK_MAX_AGE))
+	w.Header().Set("ETag", fmt.Sprintf("\"%x-%x\"", modified.Unix(), len(data)))
+
+	http.ServeContent(w.(http.ResponseWriter), r.Request, "", modified, bytes.NewReader(data))
+
+	// metrics
+	metrics.Count("api.total.count", 1)
+	metrics.Count("api.artwork.count", 1)
+	metrics.Histogram("api.artwork.duration", (float64)(util.ElapsedTimeSince(start)))
+}
+
+// artworkPath is where the thumbnails of the podcast are served, empty if there are none
+func artworkPath(p *backend.PodcastMetadata) string {
+	if p.Artwork == "" {
+		return ""
+	}
+	return strings.Replace(strings.TrimSuffix(ARTWORK_ENDPOINT, "/#size"), "#id", p.Uid, 1)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"strconv"
 	"strings"
 	"time"
 
@@ -28,6 +29,11 @@ func podcast_endpoint(w rest.ResponseWriter, r *rest.Request) {
 	}
 
 	result := backend.PodcastLookup(uid)
+	if result != nil && result.MergedInto != "" {
+		// a duplicate, answer with the podcast it was merged into
+		uid = result.MergedInto
+		result = backend.PodcastLookup(uid)
+	}
 	if result == nil {
 		backend.JsonApiErrorResponse(w, "api.podcast.error", "podcast not found", nil)
 
@@ -39,6 +45,8 @@ func podcast_endpoint(w rest.ResponseWriter, r *rest.Request) {
 		return
 	}
 
+	// &removed=true includes episodes that vanished from the feed
+	removed := includeRemoved(r)
 	episodes := make([]*backend.Episode, 0)
 
 	if len(r.URL.Query()["e"]) != 0 {
@@ -46,42 +54,17 @@ func podcast_endpoint(w rest.ResponseWriter, r *rest.Request) {
 
 		if e == "l" { // e=l(atest)
 
-			result := backend.PodcastLookupLatestEpisode(uid)
+			result := backend.PodcastLookupLatestEpisode(uid, removed)
 			episodes = make([]*backend.Episode, 1)
-
-			episodes[0] = &backend.Episode{
-				result.Uid,
-				result.PodcastUid,
-				result.Title,
-				result.Url,
-				result.Description,
-				result.Published,
-				result.Duration,
-				result.Author,
-				result.AssetUrl,
-				result.AssetType,
-				result.AssetSize,
-			}
+			episodes[0] = episodeMetadataToView(result)
 
 		} else if e == "a" { // e=a(ll)
 
-			result := backend.PodcastLookupAllEpisodes(uid)
+			result := backend.PodcastLookupAllEpisodes(uid, removed)
 			episodes = make([]*backend.Episode, len(result))
 
 			for i := range result {
-				episodes[i] = &backend.Episode{
-					result[i].Uid,
-					result[i].PodcastUid,
-					result[i].Title,
-					result[i].Url,
-					result[i].Description,
-					result[i].Published,
-					result[i].Duration,
-					result[i].Author,
-					result[i].AssetUrl,
-					result[i].AssetType,
-					result[i].AssetSize,
-				}
+				episodes[i] = episodeMetadataToView(result[i])
 			}
 
 		}
@@ -98,8 +81,19 @@ func podcast_endpoint(w rest.ResponseWriter, r *rest.Request) {
 		result.Published,
 		result.Language,
 		result.ImageUrl,
+		artworkPath(result),
 		result.OwnerName,
 		result.OwnerEmail,
+		result.Tags,
+		result.Explicit,
+		result.Type,
+		result.Block,
+		result.Complete,
+		result.PodcastGuid,
+		result.Locked,
+		result.Funding,
+		result.Persons,
+		result.Value,
 		episodes,
 	}
 	backend.JsonApiResponse(w, &podcast)
@@ -122,7 +116,7 @@ func episode_endpoint(w rest.ResponseWriter, r *rest.Request) {
 	}
 
 	result := backend.EpisodeLookup(uid)
-	if result == nil {
+	if result == nil || (result.Removed != 0 && !includeRemoved(r)) {
 		backend.JsonApiErrorResponse(w, "api.episode.error", "episode not found", nil)
 
 		metrics.Error("api.episode.error", "episode not found", []string{uid})
@@ -134,23 +128,49 @@ func episode_endpoint(w rest.ResponseWriter, r *rest.Request) {
 	}
 
 	// create an 'outside' view
-	episode := backend.Episode{
-		result.Uid,
-		result.PodcastUid,
-		result.Title,
-		result.Url,
-		result.Description,
-		result.Published,
-		result.Duration,
-		result.Author,
-		result.AssetUrl,
-		result.AssetType,
-		result.AssetSize,
-	}
-	backend.JsonApiResponse(w, &episode)
+	episode := episodeMetadataToView(result)
+	backend.JsonApiResponse(w, episode)
 
 	// metrics
 	metrics.Count("api.total.count", 1)
 	metrics.Count("api.episode.count", 1)
 	metrics.Histogram("api.episode.duration", (float64)(util.ElapsedTimeSince(start)))
 }
+
+func episodeMetadataToView(e *backend.EpisodeMetadata) *backend.Episode {
+	return &backend.Episode{
+		e.Uid,
+		e.PodcastUid,
+		e.Title,
+		e.Url,
+		e.Description,
+		e.Published,
+		e.Duration,
+		e.Author,
+		e.AssetUrl,
+		e.AssetType,
+		e.AssetSize,
+		e.ImageUrl,
+		e.Explicit,
+		e.Season,
+		e.Number,
+		e.EpisodeType,
+		e.Block,
+		e.Removed,
+		e.Chapters,
+		e.ChaptersUrl,
+		e.ChaptersType,
+		e.Transcripts,
+		e.Persons,
+		e.Soundbites,
+		e.Value,
+	}
+}
+
+func includeRemoved(r *rest.Request) bool {
+	if len(r.URL.Query()["removed"]) == 0 {
+		return false
+	}
+	removed, _ := strconv.ParseBool(r.URL.Query()["removed"][0])
+	return removed
+}
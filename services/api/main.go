@@ -1,26 +1,42 @@
 package main
 
 import (
+	"context"
 	stdlog "log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/ant0ine/go-json-rest/rest"
 
+	"github.com/mindcastio/mindcastio/backend/artwork"
 	"github.com/mindcastio/mindcastio/backend/datastore"
 	"github.com/mindcastio/mindcastio/backend/environment"
+	"github.com/mindcastio/mindcastio/backend/feed"
 	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/messaging"
 	"github.com/mindcastio/mindcastio/backend/metrics"
 )
 
 const (
 	SEARCH_ENDPOINT  string = "/api/1/search"
 	SUBMIT_ENDPOINT  string = "/api/1/submit"
+	STATUS_ENDPOINT  string = "/api/1/submit/#id"
 	STATS_ENDPOINT   string = "/api/1/stats"
 	PODCAST_ENDPOINT string = "/api/1/p/#id"
 	EPISODE_ENDPOINT string = "/api/1/e/#id"
+	ARTWORK_ENDPOINT string = "/api/1/p/#id/artwork/#size"
+
+	ADMIN_ENDPOINT         string = "/api/1/admin"
+	ADMIN_FEEDS_ENDPOINT   string = ADMIN_ENDPOINT + "/feeds"
+	ADMIN_IMPORT_ENDPOINT  string = ADMIN_ENDPOINT + "/import"
+	ADMIN_EXPORT_ENDPOINT  string = ADMIN_ENDPOINT + "/export"
+	ADMIN_HISTORY_ENDPOINT string = ADMIN_ENDPOINT + "/p/#id/history"
+	ADMIN_RESUME_ENDPOINT  string = ADMIN_ENDPOINT + "/p/#id/resume"
+	ADMIN_CRAWL_ENDPOINT   string = ADMIN_ENDPOINT + "/p/#id/crawl"
+	ADMIN_RATE_ENDPOINT    string = ADMIN_ENDPOINT + "/p/#id/rate"
 )
 
 func main() {
@@ -31,17 +47,33 @@ func main() {
 	logger.Initialize()
 	metrics.Initialize(env)
 	datastore.Initialize(env)
+	messaging.Initialize(env)
+	artwork.Initialize(env)
+
+	// crawler identity and politeness towards the hosts
+	feed.Configure(env.CrawlerUserAgent(), time.Second*time.Duration(env.CrawlerHostSpacing()), env.CrawlerRobots())
+	feed.SetLimits(feed.Limits{MaxBytes: (int64)(env.CrawlerMaxFeedSize()), MaxItems: env.CrawlerMaxItems(), MaxFieldLength: env.CrawlerMaxField()})
 
 	// initilize the REST API router
 	api := rest.NewApi()
 	api.Use(rest.DefaultDevStack...)
+	api.Use(adminAuthentication(env))
 
 	router, err := rest.MakeRouter(
 		rest.Get(SEARCH_ENDPOINT, search_endpoint),
 		rest.Post(SUBMIT_ENDPOINT, submit_endpoint),
+		rest.Get(STATUS_ENDPOINT, submit_status_endpoint),
 		rest.Get(STATS_ENDPOINT, stats_endpoint),
 		rest.Get(PODCAST_ENDPOINT, podcast_endpoint),
 		rest.Get(EPISODE_ENDPOINT, episode_endpoint),
+		rest.Get(ARTWORK_ENDPOINT, artwork_endpoint),
+		rest.Get(ADMIN_FEEDS_ENDPOINT, admin_feeds_endpoint),
+		rest.Get(ADMIN_HISTORY_ENDPOINT, admin_history_endpoint),
+		rest.Post(ADMIN_RESUME_ENDPOINT, admin_resume_endpoint),
+		rest.Post(ADMIN_CRAWL_ENDPOINT, admin_crawl_endpoint),
+		rest.Put(ADMIN_RATE_ENDPOINT, admin_rate_endpoint),
+		rest.Post(ADMIN_IMPORT_ENDPOINT, admin_import_endpoint),
+		rest.Get(ADMIN_EXPORT_ENDPOINT, admin_export_endpoint),
 	)
 
 	if err != nil {
@@ -49,21 +81,41 @@ func main() {
 	}
 	api.SetApp(router)
 
-	// setup shutdown handling
+	// start the REST api
+	logger.Log("api.startup")
+	metrics.Success("mindcastio", "api.startup", nil)
+
+	server := &http.Server{Addr: env.ListenPort(), Handler: api.MakeHandler()}
+
+	failed := make(chan error, 1)
+	go func() {
+		failed <- server.ListenAndServe()
+	}()
+
+	// wait for the shutdown signal, then let the requests in flight finish
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigs
+
+	select {
+	case err := <-failed:
+		logger.Error("api.listen.error", err, env.ListenPort())
 		shutdown()
 		os.Exit(1)
-	}()
+	case <-sigs:
+	}
 
-	// start the REST api
-	logger.Log("api.startup")
-	metrics.Success("mindcastio", "api.startup", nil)
+	logger.Log("api.stopping")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(env.ShutdownGrace()))
+	defer cancel()
 
-	http.ListenAndServe(env.ListenPort(), api.MakeHandler())
+	err = server.Shutdown(ctx)
+	if err != nil {
+		logger.Warn("api.shutdown.timeout", err.Error())
+	}
 
+	shutdown()
+	os.Exit(0)
 }
 
 func shutdown() {
@@ -71,6 +123,7 @@ func shutdown() {
 	metrics.Success("mindcastio", "api.shutdown", nil)
 
 	// shutdown of services
+	messaging.Shutdown()
 	datastore.Shutdown()
 	metrics.Shutdown()
 }
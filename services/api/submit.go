@@ -8,6 +8,7 @@ import (
 	"github.com/ant0ine/go-json-rest/rest"
 
 	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/messaging"
 	"github.com/mindcastio/mindcastio/backend/metrics"
 
 	"github.com/mindcastio/mindcastio/backend/util"
@@ -17,34 +18,32 @@ type feedType struct {
 	Feed string
 }
 
+// submit_endpoint queues the feed, the crawler checks and crawls it. The job
+// in the response can be polled at the submit status endpoint.
+// With messaging available a crawler picks it up right away, otherwise with its next sweep.
 func submit_endpoint(w rest.ResponseWriter, r *rest.Request) {
 	start := time.Now()
 
 	ft := feedType{}
 
 	err := r.DecodeJsonPayload(&ft)
-	if err != nil {
+	if err != nil || strings.TrimSpace(ft.Feed) == "" {
 		backend.JsonApiErrorResponse(w, "api.submit.error", "missing parameter", err)
 
-		metrics.Error("api.submit.error", err.Error(), nil)
+		metrics.Error("api.submit.error", "missing parameter", nil)
 		metrics.Count("api.total.count", 1)
 		metrics.Count("api.submit.count", 1)
 
 		return
 	}
 
-	// remove leading & trailing whitespace
-	feed := strings.TrimSpace(ft.Feed)
-
-	// test if the feed can be crawled, otherwise discard it already
-	err = util.ValidateUrl(feed)
+	job, err := backend.SubmitJobAdd(ft.Feed)
 	if err != nil {
-		backend.JsonApiErrorResponse(w, "api.submit.error", "invalid url", err)
+		backend.JsonApiErrorResponse(w, "api.submit.error", "", err)
 		metrics.Error("api.submit.error", err.Error(), nil)
 	} else {
-		// response
-		err = backend.SubmitPodcastFeed(feed)
-		backend.StatusResponse(w, http.StatusOK)
+		messaging.Publish(messaging.SUBMIT_SUBJECT, job.Id, job.Status)
+		backend.JsonApiStatusResponse(w, http.StatusAccepted, job)
 	}
 
 	// metrics
@@ -52,3 +51,28 @@ func submit_endpoint(w rest.ResponseWriter, r *rest.Request) {
 	metrics.Count("api.submit.count", 1)
 	metrics.Histogram("api.submit.duration", (float64)(util.ElapsedTimeSince(start)))
 }
+
+func submit_status_endpoint(w rest.ResponseWriter, r *rest.Request) {
+	start := time.Now()
+
+	// get the id first
+	id := strings.Trim(r.PathParam("id"), " ")
+	if id == "" {
+		backend.JsonApiErrorResponse(w, "api.submit_status.error", "missing parameter", nil)
+		metrics.Error("api.submit_status.error", "", nil)
+		return
+	}
+
+	job := backend.SubmitJobLookup(id)
+	if job == nil {
+		backend.JsonApiErrorStatusResponse(w, http.StatusNotFound, "api.submit_status.error", "job not found", nil)
+		metrics.Error("api.submit_status.error", "job not found", []string{id})
+		return
+	}
+	backend.JsonApiResponse(w, job)
+
+	// metrics
+	metrics.Count("api.total.count", 1)
+	metrics.Count("api.submit_status.count", 1)
+	metrics.Histogram("api.submit_status.duration", (float64)(util.ElapsedTimeSince(start)))
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
@@ -9,10 +10,14 @@ import (
 	"github.com/mindcastio/mindcastio/crawler"
 
 	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/artwork"
 	"github.com/mindcastio/mindcastio/backend/datastore"
 	"github.com/mindcastio/mindcastio/backend/environment"
+	"github.com/mindcastio/mindcastio/backend/feed"
 	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/messaging"
 	"github.com/mindcastio/mindcastio/backend/metrics"
+	"github.com/mindcastio/mindcastio/backend/util"
 )
 
 func main() {
@@ -23,33 +28,74 @@ func main() {
 	logger.Initialize()
 	metrics.Initialize(env)
 	datastore.Initialize(env)
+	messaging.Initialize(env)
+	artwork.Initialize(env)
 
-	// periodic background processes
+	// crawler identity and politeness towards the hosts
+	feed.Configure(env.CrawlerUserAgent(), time.Second*time.Duration(env.CrawlerHostSpacing()), env.CrawlerRobots())
+	feed.SetLimits(feed.Limits{MaxBytes: (int64)(env.CrawlerMaxFeedSize()), MaxItems: env.CrawlerMaxItems(), MaxFieldLength: env.CrawlerMaxField()})
+
+	// periodic background processes, they also sweep up whatever messaging missed
 	background_channel := time.NewTicker(time.Second * time.Duration(backend.DEFAULT_CRAWLER_SCHEDULE)).C
+	submit_channel := time.NewTicker(time.Second * time.Duration(backend.DEFAULT_SUBMIT_SCHEDULE)).C
+	artwork_channel := time.NewTicker(time.Second * time.Duration(backend.DEFAULT_ARTWORK_SCHEDULE)).C
 
-	// setup shutdown handling
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigs
-		shutdown()
-		os.Exit(1)
-	}()
+	// cancelled on shutdown, the work in progress is tracked
+	ctx, cancel := context.WithCancel(context.Background())
+	var tracker util.Tracker
+
+	// react to events, the periodic processes catch up on anything missed
+	subscription, err := crawler.SubscribeSubmitJobs(ctx, &tracker)
+	if err != nil {
+		logger.Warn("crawler.subscribe.error", err.Error())
+	}
 
 	// start the scheduler
 	logger.Log("crawler.startup")
 	metrics.Success("mindcastio", "crawler.startup", nil)
 
-	for {
-		<-background_channel
-		crawler.SchedulePodcastCrawling()
+	tracker.Add()
+	go func() {
+		defer tracker.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-background_channel:
+				crawler.SchedulePodcastCrawling(ctx)
+			case <-submit_channel:
+				crawler.ScheduleSubmitJobs(ctx)
+			case <-artwork_channel:
+				crawler.ScheduleArtwork(ctx)
+			}
+		}
+	}()
+
+	// wait for the shutdown signal, then let the work in progress stop at a safe point
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	<-sigs
+
+	logger.Log("crawler.stopping")
+	cancel()
+
+	// no new messages, then wait for the ones being processed
+	if subscription != nil {
+		subscription.Unsubscribe()
 	}
+	if !tracker.Close(time.Second * time.Duration(env.ShutdownGrace())) {
+		logger.Warn("crawler.shutdown.timeout")
+	}
+
+	shutdown()
+	os.Exit(0)
 }
 
 func shutdown() {
 	logger.Log("crawler.shutdown")
 	metrics.Success("mindcastio", "crawler.shutdown", nil)
 
+	messaging.Shutdown()
 	datastore.Shutdown()
 	metrics.Shutdown()
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
@@ -12,8 +13,9 @@ import (
 	"github.com/mindcastio/mindcastio/backend/datastore"
 	"github.com/mindcastio/mindcastio/backend/environment"
 	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/messaging"
 	"github.com/mindcastio/mindcastio/backend/metrics"
-
+	"github.com/mindcastio/mindcastio/backend/util"
 )
 
 func main() {
@@ -24,34 +26,64 @@ func main() {
 	logger.Initialize()
 	metrics.Initialize(env)
 	datastore.Initialize(env)
+	messaging.Initialize(env)
 
-	// periodic background processes
+	// periodic background processes, they also sweep up whatever messaging missed
 	background_channel := time.NewTicker(time.Second * time.Duration(backend.DEFAULT_INDEXER_SCHEDULE)).C
 
-	// setup shutdown handling
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	go func() {
-		<-sigs
-		shutdown()
-		os.Exit(1)
-	}()
+	// cancelled on shutdown, the work in progress is tracked
+	ctx, cancel := context.WithCancel(context.Background())
+	var tracker util.Tracker
+
+	// react to events, the periodic processes catch up on anything missed
+	subscription, err := search.SubscribeIndexRequests(ctx, &tracker)
+	if err != nil {
+		logger.Warn("indexer.subscribe.error", err.Error())
+	}
 
 	// start the scheduler
 	logger.Log("indexer.startup")
 	metrics.Success("mindcastio", "indexer.startup", nil)
 
-	for {
-		<-background_channel
-		search.SchedulePodcastIndexing()
-		search.ScheduleEpisodeIndexing()
+	tracker.Add()
+	go func() {
+		defer tracker.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-background_channel:
+				search.SchedulePodcastIndexing(ctx)
+				search.ScheduleEpisodeIndexing(ctx)
+			}
+		}
+	}()
+
+	// wait for the shutdown signal, then let the batch in progress finish
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	<-sigs
+
+	logger.Log("indexer.stopping")
+	cancel()
+
+	// no new messages, then wait for the ones being processed
+	if subscription != nil {
+		subscription.Unsubscribe()
 	}
+	if !tracker.Close(time.Second * time.Duration(env.ShutdownGrace())) {
+		logger.Warn("indexer.shutdown.timeout")
+	}
+
+	shutdown()
+	os.Exit(0)
 }
 
 func shutdown() {
 	logger.Log("indexer.shutdown")
 	metrics.Success("mindcastio", "indexer.shutdown", nil)
 
+	messaging.Shutdown()
 	datastore.Shutdown()
 	metrics.Shutdown()
 }
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	"github.com/mindcastio/mindcastio/backend/artwork"
+)
+
+// go run tests/artwork.go
+func main() {
+
+	large := image.NewNRGBA(image.Rect(0, 0, 1400, 1000))
+	for y := 0; y < 1000; y++ {
+		for x := 0; x < 1400; x++ {
+			large.Set(x, y, color.NRGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+
+	var png_image, jpeg_image, tiny_image bytes.Buffer
+	png.Encode(&png_image, large)
+	jpeg.Encode(&jpeg_image, large, nil)
+	png.Encode(&tiny_image, image.NewNRGBA(image.Rect(0, 0, 32, 32)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cover.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(png_image.Bytes())
+	})
+	mux.HandleFunc("/cover.jpg", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(jpeg_image.Bytes())
+	})
+	mux.HandleFunc("/tiny.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tiny_image.Bytes())
+	})
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not an image"))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "artwork")
+	defer os.RemoveAll(dir)
+	artwork.SetStore(artwork.NewLocalStore(dir))
+
+	failed := 0
+	check := func(name string, ok bool, err error) {
+		if !ok {
+			failed++
+			fmt.Printf("FAIL %s: %v\n", name, err)
+		}
+	}
+
+	thumbnail := func(uid string, size string) (image.Config, string) {
+		data, _, err := artwork.GetStore().Get(artwork.Key(uid, size))
+		if err != nil {
+			return image.Config{}, ""
+		}
+		config, format, _ := image.DecodeConfig(bytes.NewReader(data))
+		return config, format
+	}
+
+	err := artwork.Process("p1", server.URL+"/cover.png")
+	config, format := thumbnail("p1", "large")
+	check("png large", err == nil && format == "png" && config.Width == 600 && config.Height == 428, err)
+	config, format = thumbnail("p1", "small")
+	check("png small", err == nil && format == "png" && config.Width == 100 && config.Height == 71, err)
+
+	err = artwork.Process("p2", server.URL+"/cover.jpg")
+	config, format = thumbnail("p2", "medium")
+	check("jpeg medium", err == nil && format == "jpeg" && config.Width == 300, err)
+
+	err = artwork.Process("p3", server.URL+"/tiny.png")
+	check("too small", err == artwork.ErrArtworkSize, err)
+
+	err = artwork.Process("p4", server.URL+"/text")
+	check("not an image", err == artwork.ErrArtworkFormat, err)
+
+	err = artwork.Process("p5", "ftp://example.com/cover.png")
+	check("invalid url", err == artwork.ErrArtworkUrl, err)
+
+	_, _, err = artwork.GetStore().Get("../p1/large")
+	check("invalid key", err == artwork.ErrInvalidKey, err)
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("ok")
+}
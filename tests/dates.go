@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mindcastio/mindcastio/backend/feed"
+)
+
+// go run tests/dates.go [file]
+func main() {
+
+	file := "tests/fixtures/dates.txt"
+	if len(os.Args) > 1 {
+		file = os.Args[1]
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		fmt.Println(file, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	failed := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// input|expected
+		parts := strings.SplitN(line, "|", 2)
+		t, err := feed.RSSDate(parts[0]).Parse()
+
+		result := "-"
+		if err == nil {
+			result = t.Format(time.RFC3339Nano)
+		} else if err != feed.ErrUnparseableDate {
+			result = err.Error()
+		}
+
+		if result != parts[1] {
+			failed++
+			fmt.Printf("FAIL %q: got %s, expected %s\n", parts[0], result, parts[1])
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("ok")
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mindcastio/mindcastio/backend/feed"
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+// go run tests/feed.go [file ...]
+func main() {
+
+	files := []string{"tests/fixtures/rss.xml", "tests/fixtures/atom.xml"}
+	if len(os.Args) > 1 {
+		files = os.Args[1:]
+	}
+
+	for _, f := range files {
+		r, err := os.Open(f)
+		if err != nil {
+			fmt.Println(f, err)
+			continue
+		}
+
+		channel, err := feed.Parse(r)
+		r.Close()
+
+		if err != nil {
+			fmt.Println(f, err)
+			continue
+		}
+
+		for _, item := range channel.Item {
+			if len(item.Enclosure) == 0 {
+				fmt.Println(f, "missing enclosure:", item.Title)
+			}
+		}
+
+		util.PrettyPrintJson(channel)
+		fmt.Println()
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mindcastio/mindcastio/backend/feed"
+)
+
+const (
+	ETAG          = `"abc123"`
+	LAST_MODIFIED = "Mon, 02 Jan 2006 15:04:05 GMT"
+)
+
+// go run tests/fetch.go [file]
+func main() {
+
+	file := "tests/fixtures/rss.xml"
+	if len(os.Args) > 1 {
+		file = os.Args[1]
+	}
+
+	flaky := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, file)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/feed.xml", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/found", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/moved", http.StatusFound)
+	})
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		flaky++
+		if flaky == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeFile(w, r, file)
+	})
+	mux.HandleFunc("/conditional", func(w http.ResponseWriter, r *http.Request) {
+		// 304 only if the request carries the validators of the last response
+		if r.Header.Get("If-None-Match") == ETAG && r.Header.Get("If-Modified-Since") == LAST_MODIFIED {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		data, _ := ioutil.ReadFile(file)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("ETag", ETAG)
+		w.Header().Set("Last-Modified", LAST_MODIFIED)
+		w.Write(data)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<!DOCTYPE html><html><head><link rel="alternate" type="application/rss+xml" href="/feed.xml"></head><body></body></html>`))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte{0x89, 'P', 'N', 'G'})
+	})
+	mux.HandleFunc("/many", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(`<rss version="2.0"><channel><title>Many</title>`))
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, `<item><title>Episode %d with a rather long title</title><guid>%d</guid></item>`, i, i)
+		}
+		w.Write([]byte(`</channel></rss>`))
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /\n\nUser-agent: mindcastio\nDisallow: /secret\nAllow: /secret/*.xml$\n"))
+	})
+	mux.HandleFunc("/secret/", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.UserAgent(), "mindcastio/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.ServeFile(w, r, file)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	failed := 0
+	check := func(name string, ok bool, resp *feed.Response, err error) {
+		if !ok {
+			failed++
+			fmt.Printf("FAIL %s: %+v %v\n", name, resp, err)
+		}
+	}
+
+	channel, resp, err := feed.Fetch(nil, &feed.Request{Url: server.URL + "/conditional"})
+	check("validators", err == nil && channel != nil && resp.ETag == ETAG && resp.LastModified == LAST_MODIFIED, resp, err)
+
+	channel, resp, err = feed.Fetch(nil, &feed.Request{Url: server.URL + "/conditional", ETag: ETAG, LastModified: LAST_MODIFIED})
+	check("not modified", err == nil && channel == nil && resp.NotModified(), resp, err)
+
+	channel, resp, err = feed.Fetch(nil, &feed.Request{Url: server.URL + "/moved"})
+	check("permanent redirect", err == nil && channel != nil && resp.Moved == server.URL+"/feed.xml", resp, err)
+
+	channel, resp, err = feed.Fetch(nil, &feed.Request{Url: server.URL + "/found"})
+	check("temporary redirect", err == nil && channel != nil && resp.Moved == "" && resp.Url == server.URL+"/feed.xml", resp, err)
+
+	channel, resp, err = feed.Fetch(nil, &feed.Request{Url: server.URL + "/flaky"})
+	check("retry", err == nil && channel != nil && resp.Attempts == 2, resp, err)
+
+	channel, resp, err = feed.Fetch(nil, &feed.Request{Url: server.URL + "/gone"})
+	check("no retry", err != nil && resp.Attempts == 1, resp, err)
+
+	resp, err = feed.Probe(nil, server.URL+"/feed.xml")
+	check("probe", err == nil && resp.Status == http.StatusOK, resp, err)
+
+	resp, err = feed.Probe(nil, server.URL+"/gone")
+	check("probe gone", err != nil && resp.Status == http.StatusGone, resp, err)
+
+	channel, resp, err = feed.Resolve(nil, server.URL+"/page")
+	check("discover", err == nil && channel != nil && resp.Url == server.URL+"/feed.xml", resp, err)
+
+	channel, resp, err = feed.Resolve(nil, server.URL+"/found")
+	check("resolve feed", err == nil && channel != nil && resp.Url == server.URL+"/feed.xml", resp, err)
+
+	channel, resp, err = feed.Resolve(nil, server.URL+"/image")
+	check("not a feed", err == feed.ErrNotAFeed, resp, err)
+
+	channel, resp, err = feed.Fetch(nil, &feed.Request{Url: server.URL + "/image"})
+	check("content type", err == feed.ErrContentType && feed.Classify(err, resp) == feed.CLASS_CONTENT_TYPE, resp, err)
+
+	// limits
+	feed.SetLimits(feed.Limits{MaxBytes: 1024})
+
+	channel, resp, err = feed.Fetch(nil, &feed.Request{Url: server.URL + "/feed.xml"})
+	check("too large", err == feed.ErrFeedTooLarge && feed.Classify(err, resp) == feed.CLASS_TOO_LARGE, resp, err)
+
+	feed.SetLimits(feed.Limits{MaxItems: 1, MaxFieldLength: 16})
+
+	channel, resp, err = feed.Fetch(nil, &feed.Request{Url: server.URL + "/many"})
+	check("truncated", err == nil && len(channel.Item) == 1 && resp.Items == 3 && len(channel.Item[0].Title) <= 16 && feed.Classify(err, resp) == feed.CLASS_TRUNCATED, resp, err)
+
+	feed.SetLimits(feed.Limits{MaxBytes: feed.DEFAULT_MAX_BYTES, MaxItems: feed.DEFAULT_MAX_ITEMS, MaxFieldLength: feed.DEFAULT_MAX_FIELD_LENGTH})
+
+	// politeness
+	feed.Configure("mindcastio/1.0 (+https://mindcast.io/crawler)", 0, true)
+
+	channel, resp, err = feed.Fetch(nil, &feed.Request{Url: server.URL + "/secret/feed.xml"})
+	check("robots allowed", err == nil && channel != nil, resp, err)
+
+	channel, resp, err = feed.Fetch(nil, &feed.Request{Url: server.URL + "/secret/feed"})
+	check("robots disallowed", err == feed.ErrDisallowed && resp.Attempts == 0, resp, err)
+
+	feed.Configure("", 30*time.Second, false)
+
+	channel, resp, err = feed.Fetch(nil, &feed.Request{Url: server.URL + "/feed.xml"})
+	check("host spacing", err == nil && channel != nil, resp, err)
+
+	channel, resp, err = feed.Fetch(nil, &feed.Request{Url: server.URL + "/feed.xml"})
+	check("host deferred", err == feed.ErrDeferred && feed.IsDeferred(err), resp, err)
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("ok")
+}
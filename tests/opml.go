@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/mindcastio/mindcastio/backend/feed"
+)
+
+// go run tests/opml.go [file]
+func main() {
+
+	file := "tests/fixtures/podcasts.opml"
+	if len(os.Args) > 1 {
+		file = os.Args[1]
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		fmt.Println(file, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	urls, err := feed.ParseOPML(f)
+	if err != nil {
+		fmt.Println("FAIL parse:", err)
+		os.Exit(1)
+	}
+	for i := range urls {
+		fmt.Println(urls[i])
+	}
+
+	// write and read back
+	outlines := make([]feed.Outline, len(urls))
+	for i := range urls {
+		outlines[i] = feed.Outline{Text: urls[i], Title: urls[i], Type: "rss", XmlUrl: urls[i]}
+	}
+
+	var b bytes.Buffer
+	err = feed.WriteOPML(&b, "test", outlines)
+	if err != nil {
+		fmt.Println("FAIL write:", err)
+		os.Exit(1)
+	}
+
+	again, err := feed.ParseOPML(&b)
+	if err != nil || len(again) != len(urls) {
+		fmt.Println("FAIL round trip:", err, len(again), len(urls))
+		os.Exit(1)
+	}
+	fmt.Println("ok")
+}
@@ -10,6 +10,6 @@ func main() {
 
 	url := "http://jerrywho.podOmatic.com/rss2.xml"
 
-	channel, _ := feed.RSS(url)
+	channel, _ := feed.RSS(url, feed.DefaultFetcher)
 	fmt.Println(channel)
 }
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+// go run tests/urls.go [file]
+func main() {
+
+	file := "tests/fixtures/urls.txt"
+	if len(os.Args) > 1 {
+		file = os.Args[1]
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		fmt.Println(file, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	failed := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// input|normalized|canonical
+		parts := strings.SplitN(line, "|", 3)
+
+		normalized, err := util.NormalizeUrl(parts[0])
+		if err != nil {
+			normalized = "-"
+		}
+		canonical, err := util.CanonicalUrl(parts[0])
+		if err != nil {
+			canonical = "-"
+		}
+
+		if normalized != parts[1] || canonical != parts[2] {
+			failed++
+			fmt.Printf("FAIL %q: got %s %s, expected %s %s\n", parts[0], normalized, canonical, parts[1], parts[2])
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("ok")
+}
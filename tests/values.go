@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/backend"
+)
+
+// go run tests/values.go
+func main() {
+
+	// as parsed from the feed, without nested lists
+	fresh := backend.EpisodeMetadata{
+		Uid:         "1",
+		Title:       "Episode",
+		Chapters:    []backend.Chapter{},
+		Persons:     nil,
+		Value:       &backend.Value{Type: "lightning", Method: "keysend", Suggested: "0.1"},
+		Transcripts: []backend.Transcript{{Url: "http://example.com/t.vtt", Type: "text/vtt"}},
+	}
+
+	// as read back from the datastore
+	data, err := bson.Marshal(fresh)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	stored := backend.EpisodeMetadata{}
+	err = bson.Unmarshal(data, &stored)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	check := func(name string, ok bool) {
+		if !ok {
+			failed++
+			fmt.Printf("FAIL %s\n", name)
+		}
+	}
+
+	check("chapters", backend.SameValues(stored.Chapters, fresh.Chapters))
+	check("persons", backend.SameValues(stored.Persons, fresh.Persons))
+	check("value", backend.SameValues(stored.Value, fresh.Value))
+	check("transcripts", backend.SameValues(stored.Transcripts, fresh.Transcripts))
+
+	changed := *fresh.Value
+	changed.Recipients = []backend.ValueRecipient{{Name: "host", Type: "node", Address: "abc", Split: 100}}
+	check("value changed", !backend.SameValues(stored.Value, &changed))
+	check("value removed", !backend.SameValues(stored.Value, (*backend.Value)(nil)))
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("ok")
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/crawler"
+	"github.com/mindcastio/mindcastio/search"
+
+	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/datastore"
+	"github.com/mindcastio/mindcastio/backend/environment"
+	"github.com/mindcastio/mindcastio/backend/feed"
+	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/metrics"
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+/*
+	migration_02
+
+	Re-key all episodes from the title fingerprint to the guid based identity.
+	Episodes that are already in the search index are moved to their new id.
+
+	The podcasts are processed in the order of their uid, an interrupted run is resumed
+	with the uid of the last podcast it logged: go run migration_002.go [uid]
+*/
+
+func main() {
+
+	// environment setup
+	env := environment.GetEnvironment()
+	logger.Initialize()
+	metrics.Initialize(env)
+	defer metrics.Shutdown()
+	datastore.Initialize(env)
+	defer datastore.Shutdown()
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	podcast_metadata := ds.Collection(datastore.PODCASTS_COL)
+	episodes_metadata := ds.Collection(datastore.EPISODES_COL)
+
+	// resume after the given podcast
+	q := bson.M{}
+	if len(os.Args) > 1 {
+		q["uid"] = bson.M{"$gt": os.Args[1]}
+		logger.Log("migration_002.resume", os.Args[1])
+	}
+
+	podcasts := []backend.PodcastMetadata{}
+	podcast_metadata.Find(q).Select(bson.M{"uid": 1, "feed": 1}).Sort("uid").All(&podcasts)
+
+	total := 0
+	skipped := 0
+
+	for i := range podcasts {
+		puid := podcasts[i].Uid
+
+		// the guids are not stored, we need the feed again
+		channel, err := feed.RSS(podcasts[i].Feed, feed.DefaultFetcher)
+		if err != nil {
+			logger.Error("migration_002.error", err, puid, podcasts[i].Feed)
+			continue
+		}
+
+		// items without guid, enclosure and date that share a title get the same new uid, and items
+		// that share a title had the same old one. Which episode is which is unknown, they keep the old uid.
+		old_uids := make(map[string]int)
+		new_uids := make(map[string]int)
+		for j := range channel.Item {
+			old_uids[util.Fingerprint(channel.Item[j].Title, puid)]++
+			new_uids[crawler.EpisodeUid(&channel.Item[j], puid)]++
+		}
+
+		count := 0
+		for _, item := range channel.Item {
+			old_uid := util.Fingerprint(item.Title, puid)
+			new_uid := crawler.EpisodeUid(&item, puid)
+			if old_uid == new_uid {
+				continue
+			}
+
+			e := backend.EpisodeLookup(old_uid)
+			if e == nil {
+				continue
+			}
+			if old_uids[old_uid] > 1 || new_uids[new_uid] > 1 || backend.EpisodeLookup(new_uid) != nil {
+				logger.Warn("migration_002.collision", puid, old_uid, new_uid, item.Title)
+				skipped++
+				continue
+			}
+
+			// keep everything else, including the version
+			e.Uid = new_uid
+			e.Guid = strings.TrimSpace(item.GUID)
+
+			err = episodes_metadata.Update(bson.M{"uid": old_uid}, e)
+			if err != nil {
+				logger.Error("migration_002.error", err, puid, old_uid)
+				continue
+			}
+
+			if e.Version >= backend.SEARCH_REVISION {
+				err = search.EpisodeRekeySearchIndex(old_uid, e)
+				if err != nil {
+					logger.Error("migration_002.error", err, puid, old_uid)
+				}
+			}
+
+			count++
+		}
+
+		total = total + count
+		logger.Log("migration_002.podcast", puid, strconv.FormatInt((int64)(count), 10))
+	}
+
+	logger.Log("migration_002.done", strconv.FormatInt((int64)(len(podcasts)), 10), strconv.FormatInt((int64)(total), 10), strconv.FormatInt((int64)(skipped), 10))
+}
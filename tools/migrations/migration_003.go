@@ -0,0 +1,54 @@
+package main
+
+import (
+	"math"
+	"strconv"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/datastore"
+	"github.com/mindcastio/mindcastio/backend/environment"
+	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/metrics"
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+/*
+	migration_03
+
+	Suspended podcasts were parked forever, schedule them for a probe instead.
+	The probes are spread over the probe interval.
+*/
+
+func main() {
+
+	// environment setup
+	env := environment.GetEnvironment()
+	logger.Initialize()
+	metrics.Initialize(env)
+	defer metrics.Shutdown()
+	datastore.Initialize(env)
+	defer datastore.Shutdown()
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	results := []backend.PodcastIndex{}
+	main_index.Find(bson.M{"next": int64(math.MaxInt64), "errors": bson.M{"$gt": backend.MAX_ERRORS}}).All(&results)
+
+	for i := range results {
+		// the last error is the best guess we have
+		results[i].FailingSince = results[i].Updated
+		results[i].Next = util.IncT(util.Timestamp(), util.Random(env.CrawlerProbeRate()))
+
+		err := main_index.Update(bson.M{"uid": results[i].Uid}, &results[i])
+		if err != nil {
+			logger.Error("migration_003.error", err, results[i].Uid)
+		}
+	}
+
+	logger.Log("migration_003.done", strconv.FormatInt((int64)(len(results)), 10))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strconv"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/mindcastio/mindcastio/backend"
+	"github.com/mindcastio/mindcastio/backend/datastore"
+	"github.com/mindcastio/mindcastio/backend/environment"
+	"github.com/mindcastio/mindcastio/backend/logger"
+	"github.com/mindcastio/mindcastio/backend/metrics"
+	"github.com/mindcastio/mindcastio/backend/util"
+)
+
+/*
+	migration_04
+
+	Add the canonical feed url to all podcasts and merge podcasts that only differ
+	in the form of their feed url, e.g. http vs. https or www. The oldest podcast survives.
+*/
+
+func main() {
+
+	// environment setup
+	env := environment.GetEnvironment()
+	logger.Initialize()
+	metrics.Initialize(env)
+	defer metrics.Shutdown()
+	datastore.Initialize(env)
+	defer datastore.Shutdown()
+
+	ds := datastore.GetDataStore()
+	defer ds.Close()
+
+	main_index := ds.Collection(datastore.META_COL)
+
+	results := []backend.PodcastIndex{}
+	main_index.Find(nil).Sort("created").All(&results)
+
+	survivors := make(map[string]string)
+	merged := 0
+
+	for i := range results {
+		canonical, err := util.CanonicalUrl(results[i].Feed)
+		if err != nil {
+			logger.Warn("migration_004.invalid", results[i].Uid, results[i].Feed)
+			continue
+		}
+
+		err = main_index.Update(bson.M{"uid": results[i].Uid}, bson.M{"$set": bson.M{"canonical": canonical}})
+		if err != nil {
+			logger.Error("migration_004.error", err, results[i].Uid)
+			continue
+		}
+
+		// sorted by creation, the first one survives
+		uid, found := survivors[canonical]
+		if !found {
+			survivors[canonical] = results[i].Uid
+			continue
+		}
+
+		err = backend.IndexMerge(uid, results[i].Uid)
+		if err != nil {
+			logger.Error("migration_004.error", err, uid, results[i].Uid)
+			continue
+		}
+		merged++
+	}
+
+	logger.Log("migration_004.done", strconv.FormatInt((int64)(len(results)), 10), strconv.FormatInt((int64)(merged), 10))
+}
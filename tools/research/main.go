@@ -46,10 +46,14 @@ func main() {
 			feeds[i] = itunes_result[i].Feed
 		}
 
-		count, _ := backend.BulkSubmitPodcastFeed(feeds)
-		total = total + count
+		outcomes, err := backend.BulkSubmitPodcastFeed(feeds)
+		if err != nil {
+			logger.Error("re_search.error", err, results[i].Word)
+			continue
+		}
+		total = total + backend.BulkCount(outcomes, backend.BULK_ADDED)
 
-		logger.Log("re_search.search", results[i].Word, strconv.FormatInt((int64)(count), 10))
+		logger.Log("re_search.search", results[i].Word, backend.BulkSummary(outcomes))
 	}
 
 	logger.Log("re_search.done", strconv.FormatInt((int64)(len(results)), 10), strconv.FormatInt((int64)(total), 10))